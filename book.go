@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Puzzle is printable puzzle with picture of its solution
+type Puzzle struct {
+	Title      string
+	Difficulty string
+	Puzzle     image.Image
+	Solution   image.Image
+}
+
+// PageSize is size of page in points, 1/72 of inch
+type PageSize struct {
+	W, H float64
+}
+
+var (
+	A4     = PageSize{595, 842}
+	Letter = PageSize{612, 792}
+)
+
+// BookLayout places Cols x Rows puzzles on each page of Size, solutions
+// are smaller, SolutionCols x SolutionRows per page
+type BookLayout struct {
+	Title                      string
+	Size                       PageSize
+	Cols, Rows                 int
+	SolutionCols, SolutionRows int
+}
+
+const (
+	bookMargin   = 36 // half inch
+	bookHeader   = 24
+	bookLabel    = 16
+	bookFontSize = 10
+)
+
+// MazePuzzle returns maze puzzle with cw px cells and ww px walls, solution
+// shows shortest path from begin to end. Empty difficulty is guessed from
+// length of solution
+func MazePuzzle(m *Maze, title, difficulty string, cw, ww int) Puzzle {
+	path := make([]*cell, 0, m.w)
+	visited := make([]*cell, 0, m.w)
+	m.ResetVisitedCells()
+	FindShortestPath(m, m.Begin(), m.End(), &path, &visited)
+	m.ResetVisitedCells()
+	if difficulty == "" {
+		switch n := len(path); {
+		case n < 50:
+			difficulty = "easy"
+		case n < 200:
+			difficulty = "medium"
+		default:
+			difficulty = "hard"
+		}
+	}
+
+	solution := Draw(m, white, black, cw, cw, ww)
+	// square cells of path are filled, other grids get line through them
+	if _, ok := m.topology.(planar); ok {
+		for _, c := range path {
+			DrawCell(c, solution.SubImage(cellRect(c, cw, cw)).(*image.Paletted), red, black, cw, cw, ww)
+		}
+		m.drawAllMarks(solution, black, cw, cw, ww)
+	} else {
+		drawGridPath(m, solution, path, red, cw, ww)
+	}
+	return Puzzle{title, difficulty, Draw(m, white, black, cw, cw, ww), solution}
+}
+
+// SudokuPuzzle returns sudoku puzzle, it's solved if it wasn't yet. Empty
+// difficulty is guessed from number of given digits
+func SudokuPuzzle(s *Sudoku, title, difficulty string, cellSize int) (Puzzle, error) {
+	if s.result == ([9][9]int{}) {
+		if err := s.SolveWithBacktracking(); err != nil {
+			return Puzzle{}, err
+		}
+	}
+	if difficulty == "" {
+		given := 0
+		for x := range s.initPos {
+			for _, d := range s.initPos[x] {
+				if d > 0 {
+					given++
+				}
+			}
+		}
+		switch {
+		case given >= 36:
+			difficulty = "easy"
+		case given >= 28:
+			difficulty = "medium"
+		default:
+			difficulty = "hard"
+		}
+	}
+	puzzle, err := s.DrawPuzzle(cellSize)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	solution, err := s.Draw(cellSize)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	return Puzzle{title, difficulty, puzzle, solution}, nil
+}
+
+// bookText is line of text, x, y is left end of baseline in points from
+// top left corner of page
+type bookText struct {
+	x, y float64
+	size float64
+	s    string
+}
+
+// bookImage is picture fitted in rect in points
+type bookImage struct {
+	rect [4]float64 // x, y, w, h
+	img  image.Image
+}
+
+type bookPage struct {
+	texts  []bookText
+	images []bookImage
+}
+
+// pages lays out puzzles and then their solutions
+func (l BookLayout) pages(puzzles []Puzzle) []bookPage {
+	if l.Size == (PageSize{}) {
+		l.Size = A4
+	}
+	if l.Cols < 1 || l.Rows < 1 {
+		l.Cols, l.Rows = 1, 2
+	}
+	if l.SolutionCols < 1 || l.SolutionRows < 1 {
+		l.SolutionCols, l.SolutionRows = 2*l.Cols, 2*l.Rows
+	}
+
+	pages := []bookPage{}
+	section := func(heading string, cols, rows int, pick func(Puzzle) image.Image) {
+		w := (l.Size.W - 2*bookMargin) / float64(cols)
+		h := (l.Size.H - 2*bookMargin - bookHeader) / float64(rows)
+		for i, p := range puzzles {
+			slot := i % (cols * rows)
+			if slot == 0 {
+				pages = append(pages, bookPage{texts: []bookText{
+					{bookMargin, bookMargin + bookFontSize + 4, bookFontSize + 4, heading},
+					{l.Size.W - bookMargin - 40, l.Size.H - bookMargin/2, bookFontSize,
+						fmt.Sprintf("page %d", len(pages)+1)},
+				}})
+			}
+			page := &pages[len(pages)-1]
+			x := bookMargin + float64(slot%cols)*w
+			y := bookMargin + bookHeader + float64(slot/cols)*h
+			label := fmt.Sprintf("#%d %s - %s", i+1, p.Title, p.Difficulty)
+			page.texts = append(page.texts, bookText{x, y + bookFontSize, bookFontSize, label})
+			page.images = append(page.images, bookImage{fit(pick(p), x, y+bookLabel, w-8, h-bookLabel-8), pick(p)})
+		}
+	}
+	section(l.Title, l.Cols, l.Rows, func(p Puzzle) image.Image { return p.Puzzle })
+	section("Solutions", l.SolutionCols, l.SolutionRows, func(p Puzzle) image.Image { return p.Solution })
+	return pages
+}
+
+// fit returns rect of image scaled to fit in w x h box at x, y keeping its
+// aspect ratio, centered horizontally
+func fit(img image.Image, x, y, w, h float64) [4]float64 {
+	iw, ih := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	scale := w / iw
+	if h/ih < scale {
+		scale = h / ih
+	}
+	return [4]float64{x + (w-iw*scale)/2, y, iw * scale, ih * scale}
+}
+
+// WriteBookPDF writes puzzles and solutions as PDF document, pictures are
+// embedded as compressed RGB images and text uses built in Helvetica font
+func WriteBookPDF(w io.Writer, puzzles []Puzzle, l BookLayout) error {
+	pdf := &pdfWriter{w: w}
+	pages := l.pages(puzzles)
+	size := l.Size
+	if size == (PageSize{}) {
+		size = A4
+	}
+
+	// objects 1 catalog, 2 pages, 3 font, then page, content and images
+	pdf.header()
+	pageIDs := []int{}
+	next := 4
+	for _, p := range pages {
+		pageIDs = append(pageIDs, next)
+		next += 2 + len(p.images)
+	}
+	kids := []string{}
+	for _, id := range pageIDs {
+		kids = append(kids, fmt.Sprintf("%d 0 R", id))
+	}
+	pdf.object(1, "<< /Type /Catalog /Pages 2 0 R >>", nil)
+	pdf.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)), nil)
+	pdf.object(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+
+	for i, p := range pages {
+		id := pageIDs[i]
+		content := &bytes.Buffer{}
+		xobjects := []string{}
+		for j, img := range p.images {
+			r := img.rect
+			fmt.Fprintf(content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", r[2], r[3], r[0], size.H-r[1]-r[3], j)
+			xobjects = append(xobjects, fmt.Sprintf("/Im%d %d 0 R", j, id+2+j))
+		}
+		for _, t := range p.texts {
+			s, err := winAnsi(t.s)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(content, "BT /F1 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", t.size, t.x, size.H-t.y, pdfEscape(s))
+		}
+		pdf.object(id, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R >> /XObject << %s >> >> /Contents %d 0 R >>",
+			size.W, size.H, strings.Join(xobjects, " "), id+1), nil)
+		pdf.object(id+1, fmt.Sprintf("<< /Length %d >>", content.Len()), content.Bytes())
+		for j, img := range p.images {
+			data, err := rgbDeflate(img.img)
+			if err != nil {
+				return err
+			}
+			b := img.img.Bounds()
+			pdf.object(id+2+j, fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d "+
+				"/ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>",
+				b.Dx(), b.Dy(), len(data)), data)
+		}
+	}
+	pdf.trailer(next - 1)
+	return pdf.err
+}
+
+// pdfWriter writes objects of PDF and remembers their offsets for xref
+type pdfWriter struct {
+	w       io.Writer
+	n       int
+	offsets map[int]int
+	err     error
+}
+
+func (p *pdfWriter) write(b []byte) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.Write(b)
+	p.n += n
+	p.err = err
+}
+
+func (p *pdfWriter) header() {
+	p.offsets = map[int]int{}
+	// binary comment marks file as binary for transfer tools
+	p.write([]byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"))
+}
+
+// object writes object id with dictionary and optional stream
+func (p *pdfWriter) object(id int, dict string, stream []byte) {
+	p.offsets[id] = p.n
+	p.write([]byte(fmt.Sprintf("%d 0 obj\n%s\n", id, dict)))
+	if stream != nil {
+		p.write([]byte("stream\n"))
+		p.write(stream)
+		p.write([]byte("\nendstream\n"))
+	}
+	p.write([]byte("endobj\n"))
+}
+
+// trailer writes cross reference table of objects 1..last
+func (p *pdfWriter) trailer(last int) {
+	xref := p.n
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "xref\n0 %d\n0000000000 65535 f \n", last+1)
+	for id := 1; id <= last; id++ {
+		fmt.Fprintf(b, "%010d 00000 n \n", p.offsets[id])
+	}
+	fmt.Fprintf(b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", last+1, xref)
+	p.write(b.Bytes())
+}
+
+// winAnsiHigh are characters of WinAnsiEncoding bytes 0x80..0x9f, others
+// above 0x9f are the same as in Latin-1, 0 is unused byte
+var winAnsiHigh = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// winAnsi returns s in WinAnsiEncoding (cp1252) of PDF font, it fails on
+// characters the font can't show
+func winAnsi(s string) (string, error) {
+	b := make([]byte, 0, len(s))
+next:
+	for _, r := range s {
+		if r >= ' ' && r < 0x7f || r >= 0xa0 && r <= 0xff {
+			b = append(b, byte(r))
+			continue
+		}
+		for i, c := range winAnsiHigh {
+			if c != 0 && c == r {
+				b = append(b, byte(0x80+i))
+				continue next
+			}
+		}
+		return "", fmt.Errorf("character %q of %q is not in WinAnsiEncoding", r, s)
+	}
+	return string(b), nil
+}
+
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
+
+// rgbDeflate returns zlib compressed RGB samples of img row by row
+func rgbDeflate(img image.Image) ([]byte, error) {
+	b := img.Bounds()
+	buf := &bytes.Buffer{}
+	z := zlib.NewWriter(buf)
+	row := make([]byte, 3*b.Dx())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			// transparent is printed as paper
+			if c.A == 0 {
+				c = color.NRGBA{255, 255, 255, 255}
+			}
+			i := 3 * (x - b.Min.X)
+			row[i], row[i+1], row[i+2] = c.R, c.G, c.B
+		}
+		if _, err := z.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// BookPages renders puzzles and solutions to page images at dpi dots per
+// inch
+func BookPages(puzzles []Puzzle, l BookLayout, dpi float64) []*image.RGBA {
+	size := l.Size
+	if size == (PageSize{}) {
+		size = A4
+	}
+	k := dpi / 72
+	imgs := []*image.RGBA{}
+	for _, p := range l.pages(puzzles) {
+		page := image.NewRGBA(image.Rect(0, 0, int(size.W*k), int(size.H*k)))
+		draw.Draw(page, page.Bounds(), image.NewUniform(white), image.Point{}, draw.Src)
+		for _, img := range p.images {
+			r := img.rect
+			dst := image.Rect(int(r[0]*k), int(r[1]*k), int((r[0]+r[2])*k), int((r[1]+r[3])*k))
+			draw.NearestNeighbor.Scale(page, dst, img.img, img.img.Bounds(), draw.Over, nil)
+		}
+		for _, t := range p.texts {
+			drawText(page, t, k)
+		}
+		imgs = append(imgs, page)
+	}
+	return imgs
+}
+
+// drawText draws text with basic bitmap font scaled close to its size
+func drawText(page *image.RGBA, t bookText, k float64) {
+	face := basicfont.Face7x13
+	scale := int(t.size*k/13 + 0.5)
+	if scale < 1 {
+		scale = 1
+	}
+	w := font.MeasureString(face, t.s).Ceil()
+	text := image.NewRGBA(image.Rect(0, 0, w, 13))
+	d := &font.Drawer{Dst: text, Src: image.NewUniform(color.Black), Face: face, Dot: fixed.P(0, face.Ascent)}
+	d.DrawString(t.s)
+	x, y := int(t.x*k), int(t.y*k)-face.Ascent*scale
+	dst := image.Rect(x, y, x+w*scale, y+13*scale)
+	draw.NearestNeighbor.Scale(page, dst, text, text.Bounds(), draw.Over, nil)
+}
+
+// WriteBookPNGs writes pages of book to dir as page-001.png and so on
+func WriteBookPNGs(dir string, puzzles []Puzzle, l BookLayout, dpi float64) error {
+	for i, page := range BookPages(puzzles, l, dpi) {
+		f, err := os.Create(filepath.Join(dir, fmt.Sprintf("page-%03d.png", i+1)))
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(f, page); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func bookPuzzles(t *testing.T) []Puzzle {
+	puzzles := []Puzzle{}
+	for i := 0; i < 3; i++ {
+		m, _ := NewMaze(8, 6, point{0, 0}, point{7, 5}, DFS(NewStack(), int64(i)))
+		puzzles = append(puzzles, MazePuzzle(m, "Maze", "", 10, 2))
+	}
+	s, err := SudokuPuzzle(NewSudoku(initPos), "Sudoku", "", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(puzzles, s)
+}
+
+func TestPuzzleDifficulty(t *testing.T) {
+	puzzles := bookPuzzles(t)
+	if puzzles[0].Difficulty != "easy" {
+		t.Errorf("expected easy small maze, got %s", puzzles[0].Difficulty)
+	}
+	// 34 digits given
+	if puzzles[3].Difficulty != "medium" {
+		t.Errorf("expected medium sudoku, got %s", puzzles[3].Difficulty)
+	}
+	m, _ := NewMaze(8, 6, point{0, 0}, point{7, 5}, DFS(NewStack(), 1))
+	if p := MazePuzzle(m, "Maze", "tricky", 10, 2); p.Difficulty != "tricky" {
+		t.Errorf("difficulty should be kept, got %s", p.Difficulty)
+	}
+}
+
+func TestMazePuzzleSolution(t *testing.T) {
+	m, _ := NewMaze(8, 6, point{0, 0}, point{7, 5}, DFS(NewStack(), 1))
+	p := MazePuzzle(m, "Maze", "", 10, 2)
+	// middle of end cell is filled on solution only
+	r, g, b, _ := p.Solution.At(75, 55).RGBA()
+	if r>>8 != 255 || g != 0 || b != 0 {
+		t.Errorf("expected path on solution, got %v", p.Solution.At(75, 55))
+	}
+	if c := color.RGBAModel.Convert(p.Puzzle.At(75, 55)); c != white {
+		t.Errorf("expected blank puzzle, got %v", c)
+	}
+}
+
+func TestMazePuzzleSolutionOnGrids(t *testing.T) {
+	for name, gen := range map[string]func(*Maze) (*Maze, []*cell){
+		"hex":   Hexagonal(DFS(NewStack(), 1)),
+		"tri":   Triangular(DFS(NewStack(), 1)),
+		"polar": Polar(DFS(NewStack(), 1)),
+	} {
+		m, _ := NewMaze(PolarWidth(5), 5, point{0, 0}, point{0, 4}, gen)
+		p := MazePuzzle(m, "Maze", "", 20, 2)
+		if n := countColor(p.Puzzle, red); n != 0 {
+			t.Errorf("%s: expected blank puzzle, got %d red pixels", name, n)
+		}
+		if n := countColor(p.Solution, red); n < 20 {
+			t.Errorf("%s: expected path on solution, got %d red pixels", name, n)
+		}
+	}
+}
+
+func countColor(img image.Image, c color.Color) int {
+	n := 0
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if sameColor(img.At(x, y), c) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestBookLayout(t *testing.T) {
+	puzzles := bookPuzzles(t)
+	pages := BookLayout{Title: "Puzzles", Cols: 1, Rows: 2}.pages(puzzles)
+	// 2 pages of puzzles and 1 page of 4 smaller solutions
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(pages))
+	}
+	if len(pages[0].images) != 2 || len(pages[2].images) != 4 {
+		t.Errorf("unexpected images per page %d, %d", len(pages[0].images), len(pages[2].images))
+	}
+	if pages[2].texts[0].s != "Solutions" || pages[1].texts[2].s != "#3 Maze - easy" {
+		t.Errorf("unexpected texts %v %v", pages[2].texts[0], pages[1].texts[2])
+	}
+	for _, p := range pages {
+		for _, img := range p.images {
+			r := img.rect
+			if r[0] < bookMargin || r[1] < bookMargin || r[0]+r[2] > A4.W-bookMargin || r[1]+r[3] > A4.H-bookMargin {
+				t.Errorf("image %v out of margins", r)
+			}
+		}
+	}
+}
+
+func TestWriteBookPDF(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := WriteBookPDF(buf, bookPuzzles(t), BookLayout{Title: "Puzzles (1)", Size: Letter}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.String()
+	if !strings.HasPrefix(pdf, "%PDF-1.4") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatal("not a PDF")
+	}
+	if !strings.Contains(pdf, "/Count 3") || !strings.Contains(pdf, "/MediaBox [0 0 612 792]") {
+		t.Error("expected 3 letter pages")
+	}
+	if !strings.Contains(pdf, `(Puzzles \(1\)) Tj`) {
+		t.Error("expected escaped title")
+	}
+
+	// every xref entry points to its object
+	start, err := strconv.Atoi(regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(pdf)[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(pdf[start:], -1)
+	if len(entries) == 0 {
+		t.Fatal("empty xref")
+	}
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(e[1])
+		if !strings.HasPrefix(pdf[offset:], strconv.Itoa(i+1)+" 0 obj") {
+			t.Errorf("xref of object %d points to %q", i+1, pdf[offset:offset+10])
+		}
+	}
+
+	// font shows cp1252 text only
+	buf.Reset()
+	if err := WriteBookPDF(buf, bookPuzzles(t), BookLayout{Title: "Rätsel – 1"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "(R\xe4tsel \x96 1) Tj") {
+		t.Error("expected title in WinAnsiEncoding")
+	}
+	if err := WriteBookPDF(&bytes.Buffer{}, bookPuzzles(t), BookLayout{Title: "迷路"}); err == nil {
+		t.Error("expected error for title out of WinAnsiEncoding")
+	}
+}
+
+func TestWriteBookPNGs(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteBookPNGs(dir, bookPuzzles(t), BookLayout{}, 72); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.png"))
+	// 2 pages of puzzles and 1 of solutions
+	if len(files) != 3 {
+		t.Fatalf("expected 3 pages, got %v", files)
+	}
+
+	pages := BookPages(bookPuzzles(t), BookLayout{}, 144)
+	if b := pages[0].Bounds(); b.Dx() != 1190 || b.Dy() != 1684 {
+		t.Errorf("unexpected A4 page size %v", b)
+	}
+	// label is printed in top left corner of slot
+	dark := 0
+	for y := 2 * (bookMargin + bookHeader); y < 2*(bookMargin+bookHeader+bookLabel); y++ {
+		for x := 2 * bookMargin; x < 2*bookMargin+100; x++ {
+			if r, _, _, _ := pages[0].At(x, y).RGBA(); r < 0x8000 {
+				dark++
+			}
+		}
+	}
+	if dark == 0 {
+		t.Error("expected label text")
+	}
+}
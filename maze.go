@@ -1,12 +1,14 @@
 package main
 
 import (
+	"container/heap"
+	"fmt"
 	"image"
 	"image/color"
 	"image/color/palette"
+	"image/draw"
 	"image/gif"
 	"math/rand"
-	"strings"
 )
 
 var (
@@ -20,23 +22,24 @@ var (
 
 func DFS(stack *stack, seed int64) func(*Maze) (*Maze, []*cell) {
 	return func(m *Maze) (*Maze, []*cell) {
-		rand.Seed(seed)
+		r := rand.New(rand.NewSource(seed))
 		genPath := make([]*cell, 0, m.w*m.h)
 		current := m.Begin()
 		current.visited = true
 		filter := func(c *cell) bool {
 			return !c.visited
 		}
-		for {
+		for !m.halted() {
 			unvisited := m.AdjacentCells(current, filter)
 			if len(unvisited) > 0 {
 				genPath = append(genPath, current)
-				next := unvisited[rand.Intn(len(unvisited))]
+				next := unvisited[r.Intn(len(unvisited))]
 				stack.Push(current)
 				m.RmWall(current, next)
 				current = next
 				current.visited = true
 			} else if stack.Len() > 0 {
+				m.emit(Event{Kind: Backtrack, Cell: current})
 				current = stack.Pop()
 			} else {
 				break
@@ -46,6 +49,43 @@ func DFS(stack *stack, seed int64) func(*Maze) (*Maze, []*cell) {
 	}
 }
 
+// BinaryTree returns generator which opens wall up or right of every cell
+// at random, top row and right column are single corridors. Mazes are
+// biased toward top right corner
+func BinaryTree(seed int64) func(*Maze) (*Maze, []*cell) {
+	return func(m *Maze) (*Maze, []*cell) {
+		g, ok := m.topology.(squareGrid)
+		if h, v := g.wraps(m); !ok || h || v {
+			panic("binary tree needs square grid without wrap")
+		}
+		r := rand.New(rand.NewSource(seed))
+		genPath := make([]*cell, 0, m.w*m.h)
+		for y := 0; y < m.h; y++ {
+			for x := 0; x < m.w; x++ {
+				c := m.cells[x][y]
+				if c.masked {
+					panic("binary tree doesn't support masked cells")
+				}
+				if m.halted() {
+					return m, genPath
+				}
+				genPath = append(genPath, c)
+				up, right := y > 0, x < m.w-1
+				if up && right {
+					up = r.Intn(2) == 0
+					right = !up
+				}
+				if up {
+					m.RmWall(c, m.cells[x][y-1])
+				} else if right {
+					m.RmWall(c, m.cells[x+1][y])
+				}
+			}
+		}
+		return m, genPath
+	}
+}
+
 // returns path and all visited cells
 // DFS recur search
 func FindPath(m *Maze, start, end *cell, path, visited *[]*cell) bool {
@@ -55,9 +95,15 @@ func FindPath(m *Maze, start, end *cell, path, visited *[]*cell) bool {
 	start.visited = true
 	*path = append(*path, start)
 	*visited = append(*visited, start)
+	m.emit(Event{Kind: Visit, Cell: start})
 	if start == end {
+		m.emit(Event{Kind: Found, Cell: end, Path: *path})
 		return true
 	}
+	// stopped search keeps current branch as path
+	if m.halted() {
+		return false
+	}
 	//filter connected cells
 	unvisited := m.AdjacentCells(start, func(c *cell) bool {
 		return isConnected(start, c) && !c.visited
@@ -67,38 +113,45 @@ func FindPath(m *Maze, start, end *cell, path, visited *[]*cell) bool {
 		if FindPath(m, next, end, path, visited) {
 			return true
 		}
+		if m.halted() {
+			return false
+		}
 	}
 
 	// cell is not part of path
 	*path = (*path)[:len(*path)-1]
+	m.emit(Event{Kind: Backtrack, Cell: start})
 
 	return false
 }
 
 // returns path and all visited cells
-// BFS search with storing dist for each cell from start point
+// BFS search with storing for each cell the cell it was reached from
 func FindShortestPath(m *Maze, start, end *cell, path, visited *[]*cell) bool {
 	q := make([]*cell, 0, 4)
 	start.visited = true
 	*visited = append(*visited, start)
 	current := start
-	// store distance from start point
-	visitedCellDist := make(map[*cell]int)
-	stepsFromStart := 0
+	// store previous cell on the way from start point, doors may be one
+	// way so path can't be found walking backwards from end
+	parent := make(map[*cell]*cell)
 	for {
+		m.emit(Event{Kind: Visit, Cell: current})
+		if m.halted() {
+			return false
+		}
 
 		//filter connected, not visited cells
 		unvisited := m.AdjacentCells(current, func(c *cell) bool {
 			return isConnected(current, c) && !c.visited
 		})
-		stepsFromStart++
 		// check all connected cells
 		for _, c := range unvisited {
 			c.visited = true
 			q = append(q, c)
 			*visited = append(*visited, c)
-			// store dist
-			visitedCellDist[c] = stepsFromStart
+			parent[c] = current
+			m.emit(Event{Kind: Enqueue, Cell: c, From: current})
 
 			if c != end {
 				continue
@@ -106,25 +159,11 @@ func FindShortestPath(m *Maze, start, end *cell, path, visited *[]*cell) bool {
 
 			// found end point
 			// compute shortest path backwards
-			min := 1<<31 - 1
-			for {
-				// add cell to shortest path
+			for ; c != start; c = parent[c] {
 				*path = append(*path, c)
-				if c == start {
-					break
-				}
-				// get all connected and visited cells
-				connected := m.AdjacentCells(c, func(c2 *cell) bool {
-					return isConnected(c, c2) && c2.visited
-				})
-				// find cell which is closest to start point
-				for _, next := range connected {
-					if dist := visitedCellDist[next]; min > dist {
-						c = next
-						min = dist
-					}
-				}
 			}
+			*path = append(*path, start)
+			m.emit(Event{Kind: Found, Cell: end, Path: *path})
 
 			return true
 
@@ -143,6 +182,139 @@ func FindShortestPath(m *Maze, start, end *cell, path, visited *[]*cell) bool {
 	return false
 }
 
+// returns path and all visited cells
+// A* search, visited are cells in order of expansion
+func FindShortestPathAStar(m *Maze, start, end *cell, path, visited *[]*cell) bool {
+	estimate := m.estimate(end)
+	dist := map[*cell]int{start: 0}
+	parent := make(map[*cell]*cell)
+	q := &cellQueue{}
+	heap.Push(q, cellItem{start, estimate(start)})
+	for q.Len() > 0 {
+		current := heap.Pop(q).(cellItem).c
+		if current.visited {
+			continue
+		}
+		current.visited = true
+		*visited = append(*visited, current)
+		m.emit(Event{Kind: Visit, Cell: current})
+
+		if current == end {
+			for c := end; c != start; c = parent[c] {
+				*path = append(*path, c)
+			}
+			*path = append(*path, start)
+			m.emit(Event{Kind: Found, Cell: end, Path: *path})
+			return true
+		}
+		if m.halted() {
+			return false
+		}
+
+		//filter connected, not expanded cells
+		next := m.AdjacentCells(current, func(c *cell) bool {
+			return isConnected(current, c) && !c.visited
+		})
+		for _, c := range next {
+			if d, ok := dist[c]; ok && d <= dist[current]+1 {
+				continue
+			}
+			dist[c] = dist[current] + 1
+			parent[c] = current
+			heap.Push(q, cellItem{c, dist[c] + estimate(c)})
+			m.emit(Event{Kind: Enqueue, Cell: c, From: current})
+		}
+	}
+
+	return false
+}
+
+// estimate returns A* heuristic, lower bound of steps from cell to end.
+// Links to far cells like portals and tunnels are shortcuts, any path using
+// them takes at least steps to closest one and from closest one to end
+func (m *Maze) estimate(end *cell) func(*cell) int {
+	var dist func(c1, c2 *cell) int
+	switch g := m.topology.(type) {
+	case squareGrid:
+		h, v := g.wraps(m)
+		dist = func(c1, c2 *cell) int {
+			dx, dy := abs(c1.x-c2.x), abs(c1.y-c2.y)
+			if h && m.w-dx < dx {
+				dx = m.w - dx
+			}
+			if v && m.h-dy < dy {
+				dy = m.h - dy
+			}
+			return dx + dy
+		}
+	case weaveGrid:
+		dist = func(c1, c2 *cell) int {
+			return abs(c1.x-c2.x) + abs(c1.y-c2.y)
+		}
+	case layeredGrid:
+		dist = func(c1, c2 *cell) int {
+			return abs(c1.x-c2.x) + abs(c1.y%g.lh-c2.y%g.lh) + abs(c1.y/g.lh-c2.y/g.lh)
+		}
+	default:
+		// no estimate, same as BFS
+		return func(*cell) int { return 0 }
+	}
+
+	shortcuts := []*cell{}
+	for x := range m.cells {
+		for _, c := range m.cells[x] {
+			for _, l := range c.links {
+				if !m.isAdjacent(c, l) {
+					shortcuts = append(shortcuts, c)
+					break
+				}
+			}
+		}
+	}
+	toEnd := -1
+	for _, s := range shortcuts {
+		if d := dist(s, end); toEnd < 0 || d < toEnd {
+			toEnd = d
+		}
+	}
+
+	return func(c *cell) int {
+		min := dist(c, end)
+		for _, s := range shortcuts {
+			if d := dist(c, s) + toEnd; d < min {
+				min = d
+			}
+		}
+		return min
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+type cellItem struct {
+	c        *cell
+	priority int
+}
+
+// cellQueue is min heap of cells by priority
+type cellQueue []cellItem
+
+func (q cellQueue) Len() int            { return len(q) }
+func (q cellQueue) Less(i, j int) bool  { return q[i].priority < q[j].priority }
+func (q cellQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *cellQueue) Push(x interface{}) { *q = append(*q, x.(cellItem)) }
+func (q *cellQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
 type point struct {
 	x, y int
 }
@@ -150,24 +322,81 @@ type point struct {
 type cell struct {
 	left, up, right, down bool // doors if exits
 	visited               bool
+	masked                bool    // not part of maze
+	tunnel                bool    // passage under cell, across its doors
+	links                 []*cell // doors to cells of non square grids
 	point
 }
 
+// linked returns true if there is a link from c to c2
+func (c *cell) linked(c2 *cell) bool {
+	for _, l := range c.links {
+		if l == c2 {
+			return true
+		}
+	}
+	return false
+}
+
 type Maze struct {
 	w, h        int
 	entry, exit point
 	cells       [][]*cell
+	topology    Topology
+	portals     [][2]*cell // pairs of linked cells
+	oneWay      [][2]*cell // one way doors, from and to cell
+	keys        []key
+	locks       []lock
+	observer    Observer
+	ctl         *control
 }
 
-// remove adjacent cells walls
+// remove adjacent cells walls, cells in line with a crossing between them
+// get connected by tunnel under it
 func (m *Maze) RmWall(cell1, cell2 *cell) {
-	dx := cell1.x - cell2.x
-	dy := cell1.y - cell2.y
+	defer m.emit(Event{Kind: Carve, Cell: cell2, From: cell1})
+	if mid := m.crossing(cell1, cell2); mid != nil {
+		mid.tunnel = true
+		// open tunnel mouths, mid walls stay closed
+		switch {
+		case cell1.x < cell2.x:
+			cell1.right, cell2.left = true, true
+		case cell1.x > cell2.x:
+			cell1.left, cell2.right = true, true
+		case cell1.y < cell2.y:
+			cell1.down, cell2.up = true, true
+		default:
+			cell1.up, cell2.down = true, true
+		}
+		cell1.links = append(cell1.links, cell2)
+		cell2.links = append(cell2.links, cell1)
+		return
+	}
+
 	// panic if cell not adjacent or same
-	if dx+dy == 0 || dx+dy > 1 || dx+dy < -1 {
+	if !m.isAdjacent(cell1, cell2) {
 		panic("cells not adjacent or same")
 	}
 
+	dx := cell1.x - cell2.x
+	dy := cell1.y - cell2.y
+	wrapped := false
+	if g, ok := m.topology.(squareGrid); ok {
+		dx, dy, wrapped = g.unwrap(m, dx, dy)
+	}
+	if _, ok := m.topology.(planar); !ok || dx*dx+dy*dy != 1 || wrapped {
+		// doors of non square grids, stairs and doors across wrapped
+		// edges are links
+		if !cell1.linked(cell2) {
+			cell1.links = append(cell1.links, cell2)
+			cell2.links = append(cell2.links, cell1)
+		}
+		// wrapped doors are drawn as openings in border
+		if !wrapped {
+			return
+		}
+	}
+
 	switch {
 	case dx > 0:
 		// left wall
@@ -193,31 +422,44 @@ func (m *Maze) RmWall(cell1, cell2 *cell) {
 
 // return adjecent filtered cells
 func (m *Maze) AdjacentCells(c *cell, filter func(*cell) bool) []*cell {
-	p := c.point
 	cells := []*cell{}
-	data := []struct {
-		cond bool
-		point
-	}{
-		{p.x > 0, point{p.x - 1, p.y}},
-		{p.x < m.w-1, point{p.x + 1, p.y}},
-		{p.y > 0, point{p.x, p.y - 1}},
-		{p.y < m.h-1, point{p.x, p.y + 1}},
+	neighbors := m.topology.Neighbors(m, c.point)
+	for _, p := range neighbors {
+		if next := m.cells[p.x][p.y]; !next.masked && filter(next) {
+			cells = append(cells, next)
+		}
 	}
-	for _, d := range data {
-		if d.cond && filter(m.cells[d.x][d.y]) {
-			cells = append(cells, m.cells[d.x][d.y])
+	// tunnels lead to cells which are not neighbors
+links:
+	for _, next := range c.links {
+		for _, p := range neighbors {
+			if p == next.point {
+				continue links
+			}
+		}
+		if !next.masked && filter(next) {
+			cells = append(cells, next)
 		}
 	}
 
 	return cells
 }
 
+func (m *Maze) isAdjacent(c1, c2 *cell) bool {
+	for _, p := range m.topology.Neighbors(m, c1.point) {
+		if p == c2.point {
+			return true
+		}
+	}
+	return false
+}
+
 func isConnected(c1, c2 *cell) bool {
 	if c1.y+1 == c2.y && c2.up && c1.down ||
 		c1.x-1 == c2.x && c2.right && c1.left ||
 		c1.y-1 == c2.y && c2.down && c1.up ||
-		c1.x+1 == c2.x && c2.left && c1.right {
+		c1.x+1 == c2.x && c2.left && c1.right ||
+		c1.linked(c2) {
 		return true
 	}
 	return false
@@ -235,7 +477,8 @@ func NewMaze(w, h int, entry, exit point,
 		}
 	}
 
-	m := &Maze{w: w, h: h, entry: entry, exit: exit, cells: make([][]*cell, w)}
+	m := &Maze{w: w, h: h, entry: entry, exit: exit, cells: make([][]*cell, w),
+		topology: squareGrid{}}
 
 	for x := range m.cells {
 		if m.cells[x] == nil {
@@ -271,7 +514,18 @@ func (m *Maze) End() *cell {
 func (m *Maze) String() string {
 	output, hline, vline := []byte{}, []byte{}, []byte{}
 
-	for y := 0; y < m.h; y++ {
+	// masked cells and cells outside of maze are blank
+	present := func(x, y int) bool {
+		return x >= 0 && x < m.w && y >= 0 && y < m.h && !m.cells[x][y].masked
+	}
+	corner := func(x, y int) string {
+		if present(x-1, y-1) || present(x, y-1) || present(x-1, y) || present(x, y) {
+			return "+"
+		}
+		return " "
+	}
+
+	for y := 0; y <= m.h; y++ {
 		for x := 0; x < m.w; x++ {
 			mark := " "
 			if x == m.entry.x && y == m.entry.y {
@@ -281,30 +535,73 @@ func (m *Maze) String() string {
 				mark = "E"
 
 			}
-			hElm := "+---"
+			// portal letter or one way door arrow
+			if present(x, y) && mark == " " {
+				if mk := m.mark(m.cells[x][y]); mk != "" {
+					mark = mk
+				}
+			}
+			// passage over tunnel
+			if present(x, y) && m.cells[x][y].tunnel && mark == " " {
+				mark = "-"
+				if m.cells[x][y].up {
+					mark = "|"
+				}
+			}
+			hElm := corner(x, y) + "---"
 			vElm := "| " + mark + " "
+			// locked doors show color of key
+			if present(x, y) && present(x, y-1) {
+				if l := m.lockAt(m.cells[x][y], m.cells[x][y-1]); l != nil {
+					hElm = corner(x, y) + fmt.Sprintf("-%d-", l.color)
+				}
+			}
+			if present(x, y) && present(x-1, y) {
+				if l := m.lockAt(m.cells[x][y], m.cells[x-1][y]); l != nil {
+					vElm = fmt.Sprintf("%d %s ", l.color, mark)
+				}
+			}
 
-			if m.cells[x][y].up {
-				hElm = "+   "
+			// tunnel mouth is open on one side only
+			if !present(x, y) && !present(x, y-1) ||
+				present(x, y) && (m.cells[x][y].up || present(x, y-1) && m.cells[x][y-1].down) ||
+				y == m.h && present(x, y-1) && m.cells[x][y-1].down {
+				hElm = corner(x, y) + "   "
 			}
 
-			if m.cells[x][y].left {
+			if !present(x, y) && !present(x-1, y) {
+				vElm = "    "
+			} else if present(x, y) && (m.cells[x][y].left || present(x-1, y) && m.cells[x-1][y].right) {
 				vElm = "  " + mark + " "
+			} else if !present(x, y) {
+				vElm = "|   "
 			}
 
 			hline = append(hline, []byte(hElm)...)
 			vline = append(vline, []byte(vElm)...)
 		}
 
-		output = append(output, append(hline, []byte("+\n")...)...)
-		output = append(output, append(vline, []byte("|\n")...)...)
+		hline = append(hline, []byte(corner(m.w, y)+"\n")...)
+		if present(m.w-1, y) && !m.cells[m.w-1][y].right {
+			vline = append(vline, []byte("|\n")...)
+		} else {
+			vline = append(vline, []byte(" \n")...)
+		}
+		output = append(output, hline...)
+		if y < m.h {
+			output = append(output, vline...)
+		}
 		hline, vline = hline[:0], vline[:0]
 	}
 
-	return strings.Join([]string{string(output), strings.Repeat("+---", m.w), "+\n"}, "")
+	return string(output)
 }
 
+// Draw returns maze image, cells of non square grids are cw wide
 func Draw(m *Maze, fill, border color.Color, cw, ch, ww int) *image.Paletted {
+	if _, ok := m.topology.(planar); !ok {
+		return drawGrid(m, fill, border, cw, ww)
+	}
 	r := image.Rect(0, 0, m.w*cw, m.h*cw)
 	img := image.NewPaletted(r, palette.WebSafe)
 
@@ -315,18 +612,46 @@ func Draw(m *Maze, fill, border color.Color, cw, ch, ww int) *image.Paletted {
 			DrawCell(cell, img.SubImage(rect).(*image.Paletted), fill, border, cw, ch, ww)
 		}
 	}
+	if g, ok := m.topology.(layeredGrid); ok {
+		for x := range m.cells {
+			g.drawStairs(img, m.cells[x], image.Point{}, border, cw, ch, ww)
+		}
+	}
+	m.drawAllMarks(img, border, cw, ch, ww)
 	return img
 }
 
-func DrawCell(cell *cell, img *image.Paletted,
+func DrawCell(cell *cell, img draw.Image,
 	fill, border color.Color,
 	cw, ch, ww int) {
 
 	rect := img.Bounds()
 	x0, y0, x1, y1 := rect.Min.X, rect.Min.Y, rect.Max.X, rect.Max.Y
+	// passage over tunnel is narrower than cell
+	i, j := (x1-x0)/4, (y1-y0)/4
 	for y := y0; y < y1; y++ {
 		for x := x0; x < x1; x++ {
 			img.Set(x, y, fill)
+			// masked cell is blank, walls drawn by neighbours
+			if cell.masked {
+				continue
+			}
+			// walls of passage over tunnel are solid, tunnel walls
+			// have gap under it
+			if cell.tunnel && cell.up {
+				if x >= x0+i && x < x0+i+ww || x > x1-i-ww && x <= x1-i ||
+					(y < y0+ww || y > y1-ww) && (x < x0+i || x > x1-i) {
+					img.Set(x, y, border)
+				}
+				continue
+			}
+			if cell.tunnel && cell.left {
+				if y >= y0+j && y < y0+j+ww || y > y1-j-ww && y <= y1-j ||
+					(x < x0+ww || x > x1-ww) && (y < y0+j || y > y1-j) {
+					img.Set(x, y, border)
+				}
+				continue
+			}
 			// check walls
 			if (!cell.left && x < x0+ww) ||
 				(!cell.right && x > x1-ww) ||
@@ -359,6 +684,7 @@ func AnimatePath(m *Maze, visited, path []*cell,
 			fill = fillPath // fill path diff
 		}
 		DrawCell(cell, cellImg, fill, border, cw, ch, ww)
+		m.drawMarks(cellImg, cell, image.Point{}, border, cw, ch, ww)
 		imgs = append(imgs, cellImg)
 
 	}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math/rand"
+	"time"
+)
+
+// Observation is what agent sees standing in a cell, only doors of that
+// cell with unit direction of each door. Directions are known on square
+// grids only, Dirs is nil on other grids and on levels joined by stairs
+type Observation struct {
+	Cell  *cell
+	Doors []*cell
+	Dirs  []point
+}
+
+// Strategy picks next cell to step to from doors of observation, it may
+// remember earlier observations. Returning nil means agent gives up
+type Strategy func(o Observation) *cell
+
+// Agent walks maze seeing only doors of cells it stands in
+type Agent struct {
+	m          *Maze
+	strategy   Strategy
+	current    *cell
+	trajectory []*cell
+}
+
+// NewAgent returns agent standing in start cell
+func NewAgent(m *Maze, start *cell, strategy Strategy) *Agent {
+	return &Agent{m: m, strategy: strategy, current: start, trajectory: []*cell{start}}
+}
+
+// Observe returns what agent sees in current cell
+func (a *Agent) Observe() Observation {
+	o := Observation{Cell: a.current}
+	o.Doors = a.m.AdjacentCells(a.current, func(c *cell) bool {
+		return isConnected(a.current, c)
+	})
+	switch a.m.topology.(type) {
+	case squareGrid, weaveGrid:
+	default:
+		return o
+	}
+	for _, d := range o.Doors {
+		dx, dy := a.m.direction(a.current, d)
+		o.Dirs = append(o.Dirs, point{dx, dy})
+	}
+	return o
+}
+
+// Step moves agent to cell picked by strategy and returns what it sees
+// there, it returns false if strategy gave up or picked cell behind wall
+func (a *Agent) Step() (Observation, bool) {
+	o := a.Observe()
+	next := a.strategy(o)
+	for _, d := range o.Doors {
+		if d == next {
+			a.current = next
+			a.trajectory = append(a.trajectory, next)
+			return a.Observe(), true
+		}
+	}
+	return o, false
+}
+
+// Run steps agent until it reaches end, gives up or makes limit steps,
+// returns if end was reached
+func (a *Agent) Run(end *cell, limit int) bool {
+	for i := 0; i < limit && a.current != end; i++ {
+		if _, ok := a.Step(); !ok {
+			break
+		}
+	}
+	return a.current == end
+}
+
+// Trajectory returns cells agent stood in from start, cells repeat when
+// agent comes back
+func (a *Agent) Trajectory() []*cell {
+	return a.trajectory
+}
+
+// RandomMouse returns strategy which steps through random door, it goes
+// back only from dead ends
+func RandomMouse(seed int64) Strategy {
+	r := rand.New(rand.NewSource(seed))
+	var prev *cell
+	return func(o Observation) *cell {
+		doors := make([]*cell, 0, len(o.Doors))
+		for _, d := range o.Doors {
+			if d != prev {
+				doors = append(doors, d)
+			}
+		}
+		if len(doors) == 0 {
+			doors = o.Doors
+		}
+		if len(doors) == 0 {
+			return nil
+		}
+		prev = o.Cell
+		return doors[r.Intn(len(doors))]
+	}
+}
+
+// WallFollower returns strategy which keeps hand on the wall, left or
+// right one. It finds way out of perfect maze, but may loop around
+// islands of maze with loops. It needs directions of square grid, it
+// gives up on other grids
+func WallFollower(left bool) Strategy {
+	heading := point{0, -1}
+	return func(o Observation) *cell {
+		if len(o.Dirs) != len(o.Doors) {
+			return nil
+		}
+		right := point{-heading.y, heading.x}
+		turns := []point{right, heading, {-right.x, -right.y}, {-heading.x, -heading.y}}
+		if left {
+			turns[0], turns[2] = turns[2], turns[0]
+		}
+		for _, t := range turns {
+			for i, d := range o.Dirs {
+				if d == t {
+					heading = t
+					return o.Doors[i]
+				}
+			}
+		}
+		if len(o.Doors) == 0 {
+			return nil
+		}
+		heading = o.Dirs[0]
+		return o.Doors[0]
+	}
+}
+
+// Frontier returns strategy which remembers doors it has seen and walks to
+// the closest cell it hasn't stood in yet, it gives up when all cells it
+// knows about are explored
+func Frontier() Strategy {
+	doors := map[*cell][]*cell{}
+	return func(o Observation) *cell {
+		doors[o.Cell] = o.Doors
+		// BFS over known doors to closest unexplored cell
+		parent := map[*cell]*cell{o.Cell: nil}
+		q := []*cell{o.Cell}
+		for len(q) > 0 {
+			current := q[0]
+			q = q[1:]
+			if _, explored := doors[current]; !explored {
+				for parent[current] != o.Cell {
+					current = parent[current]
+				}
+				return current
+			}
+			for _, d := range doors[current] {
+				if _, ok := parent[d]; !ok {
+					parent[d] = current
+					q = append(q, d)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// AgentFrames streams replay of agent trajectory under fog of war. Cells
+// within radius steps of agent are lit, cells seen before fade to fog color
+// in fade steps, unseen cells are fog. Like PathFrames only cells which
+// change are redrawn, frames after first hold area around agent. Cells of
+// non square grids can't be drawn, stream fails for them
+func AgentFrames(m *Maze, trajectory []*cell,
+	fill, fog, agent, border color.Color,
+	cw, ch, ww, radius, fade int, delay time.Duration) FrameStream {
+
+	if fade < 1 {
+		fade = 1
+	}
+	fill, fog, agent, border = opaque(fill), opaque(fog), opaque(agent), opaque(border)
+	shades := make([]color.Color, fade+1)
+	for i := range shades {
+		shades[i] = blend(fill, fog, float64(i)/float64(fade+1))
+	}
+	// shade of agent cell and of fog after shades
+	atAgent, inFog := -1, fade+1
+
+	return func(fn func(Frame) error) error {
+		if _, ok := m.topology.(planar); !ok {
+			return errors.New("agent frames need square grid")
+		}
+		r := image.Rect(0, 0, m.w*cw, m.h*ch)
+		canvas := image.NewRGBA(r)
+		for x := range m.cells {
+			for _, c := range m.cells[x] {
+				DrawCell(&cell{masked: true}, canvas.SubImage(cellRect(c, cw, ch)).(*image.RGBA), fog, fog, cw, ch, ww)
+			}
+		}
+		// step when cell was last lit and shade it's drawn with, cells in
+		// fog are dropped from both
+		lit := map[*cell]int{}
+		shown := map[*cell]int{}
+		for step, at := range trajectory {
+			for x := at.x - radius; x <= at.x+radius; x++ {
+				for y := at.y - radius; y <= at.y+radius; y++ {
+					if m.inside(point{x, y}) && abs(x-at.x)+abs(y-at.y) <= radius {
+						lit[m.cells[x][y]] = step
+					}
+				}
+			}
+			lit[at] = step
+			dirty := image.Rectangle{}
+			if step == 0 {
+				dirty = r
+			}
+			for c, last := range lit {
+				shade := step - last
+				switch {
+				case c == at:
+					shade = atAgent
+				case shade > fade:
+					shade = inFog
+				}
+				if old, ok := shown[c]; ok && old == shade {
+					continue
+				}
+				rect := cellRect(c, cw, ch)
+				switch shade {
+				case atAgent:
+					DrawCell(c, canvas.SubImage(rect).(*image.RGBA), agent, border, cw, ch, ww)
+				case inFog:
+					DrawCell(&cell{masked: true}, canvas.SubImage(rect).(*image.RGBA), fog, fog, cw, ch, ww)
+				default:
+					DrawCell(c, canvas.SubImage(rect).(*image.RGBA), shades[shade], border, cw, ch, ww)
+				}
+				if shade == inFog {
+					delete(lit, c)
+					delete(shown, c)
+				} else {
+					shown[c] = shade
+				}
+				dirty = dirty.Union(rect)
+			}
+			if err := fn(Frame{canvas, dirty, delay}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// blend returns color between c1 and c2, t 0 is c1 and 1 is c2
+func blend(c1, c2 color.Color, t float64) color.Color {
+	r1, g1, b1, _ := c1.RGBA()
+	r2, g2, b2, _ := c2.RGBA()
+	mix := func(a, b uint32) uint8 {
+		return uint8((float64(a)*(1-t) + float64(b)*t) / 257)
+	}
+	return color.RGBA{mix(r1, r2), mix(g1, g2), mix(b1, b2), 255}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAgent(t *testing.T) {
+	w, h := 15, 15
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 1))
+	maze.ResetVisitedCells()
+
+	strategies := map[string]Strategy{
+		"mouse":    RandomMouse(1),
+		"right":    WallFollower(false),
+		"left":     WallFollower(true),
+		"frontier": Frontier(),
+	}
+	for name, s := range strategies {
+		agent := NewAgent(maze, maze.Begin(), s)
+		if !agent.Run(maze.End(), 100*w*h) {
+			t.Errorf("%s: end not reached", name)
+			continue
+		}
+		trajectory := agent.Trajectory()
+		if trajectory[0] != maze.Begin() || trajectory[len(trajectory)-1] != maze.End() {
+			t.Errorf("%s: trajectory should go from begin to end", name)
+		}
+		for i := 1; i < len(trajectory); i++ {
+			if !isConnected(trajectory[i-1], trajectory[i]) {
+				t.Errorf("%s: walked through wall at %v", name, trajectory[i].point)
+			}
+		}
+		// perfect maze is walked at most twice along each passage
+		if name != "mouse" && len(trajectory) > 2*w*h {
+			t.Errorf("%s: too long trajectory %d", name, len(trajectory))
+		}
+	}
+	if maze.Begin().visited {
+		t.Error("agent should not use visited flags")
+	}
+}
+
+func TestAgentStep(t *testing.T) {
+	maze, _ := NewMaze(3, 1, point{0, 0}, point{2, 0}, nil)
+	maze.RmWall(maze.cells[0][0], maze.cells[1][0])
+	agent := NewAgent(maze, maze.Begin(), Frontier())
+
+	o, ok := agent.Step()
+	if !ok || o.Cell != maze.cells[1][0] || len(o.Doors) != 1 || o.Dirs[0] != (point{-1, 0}) {
+		t.Errorf("unexpected observation %v", o)
+	}
+	// everything explored, frontier gives up
+	if _, ok := agent.Step(); ok {
+		t.Error("expected agent to give up")
+	}
+	if agent.Run(maze.End(), 10) {
+		t.Error("end is walled off")
+	}
+
+	// cheating strategy can't walk through walls
+	cheat := NewAgent(maze, maze.cells[1][0], func(Observation) *cell { return maze.End() })
+	if _, ok := cheat.Step(); ok {
+		t.Error("agent walked through wall")
+	}
+}
+
+func TestAgentFrames(t *testing.T) {
+	w, h := 10, 10
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 1))
+	agent := NewAgent(maze, maze.Begin(), Frontier())
+	agent.Run(maze.End(), w*h*2)
+	trajectory := agent.Trajectory()
+
+	frames := 0
+	err := AgentFrames(maze, trajectory, white, blue, red, black, 10, 10, 2, 2, 3, time.Millisecond)(func(f Frame) error {
+		at := trajectory[frames]
+		if c := f.Canvas.At(at.x*10+5, at.y*10+5); !sameColor(c, red) {
+			t.Errorf("expected agent at %v, got %v", at.point, c)
+		}
+		if frames == 0 && !sameColor(f.Canvas.At(w*10-5, h*10-5), blue) {
+			t.Error("expected fog over unseen cells")
+		}
+		// only cells lit or faded around agent are redrawn
+		if frames > 0 && (f.Rect.Dx() > 90 || f.Rect.Dy() > 90) {
+			t.Errorf("expected small changed area, got %v", f.Rect)
+		}
+		frames++
+		return nil
+	})
+	if err != nil || frames != len(trajectory) {
+		t.Errorf("expected %d frames, got %d, %v", len(trajectory), frames, err)
+	}
+}
+
+func TestAgentOnHexGrid(t *testing.T) {
+	maze, _ := NewMaze(6, 6, point{0, 0}, point{5, 5}, Hexagonal(DFS(NewStack(), 1)))
+	maze.ResetVisitedCells()
+	if o := NewAgent(maze, maze.Begin(), nil).Observe(); len(o.Doors) == 0 || o.Dirs != nil {
+		t.Errorf("expected doors without directions, got %v", o)
+	}
+	if NewAgent(maze, maze.Begin(), WallFollower(true)).Run(maze.End(), 1000) {
+		t.Error("wall follower should give up without directions")
+	}
+	agent := NewAgent(maze, maze.Begin(), Frontier())
+	if !agent.Run(maze.End(), 1000) {
+		t.Error("end not reached")
+	}
+	err := AgentFrames(maze, agent.Trajectory(), white, blue, red, black, 10, 10, 2, 2, 3, 0)(func(Frame) error { return nil })
+	if err == nil {
+		t.Error("expected error for hex grid")
+	}
+}
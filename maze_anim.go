@@ -0,0 +1,207 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"time"
+)
+
+// Frame is a step of animation, only pixels of Canvas inside Rect may
+// differ from previous frame. Canvas is reused by stream, it's valid only
+// until callback returns
+type Frame struct {
+	Canvas *image.RGBA
+	Rect   image.Rectangle
+	Delay  time.Duration
+}
+
+// FrameStream calls fn for every frame of animation in order, it stops
+// and returns first error returned by fn
+type FrameStream func(fn func(Frame) error) error
+
+// PathFrames streams animation of visited and path cells, batch cells are
+// drawn per frame. First frame is whole maze drawn with bg fill
+func PathFrames(m *Maze, visited, path []*cell,
+	bg, fillVis, fillPath, border color.Color,
+	cw, ch, ww, batch int, delay time.Duration) FrameStream {
+
+	if batch < 1 {
+		batch = 1
+	}
+	bg, fillVis, fillPath, border = opaque(bg), opaque(fillVis), opaque(fillPath), opaque(border)
+
+	return func(fn func(Frame) error) error {
+		r := image.Rect(0, 0, m.w*cw, m.h*ch)
+		canvas := image.NewRGBA(r)
+		for x := range m.cells {
+			for _, c := range m.cells[x] {
+				DrawCell(c, canvas.SubImage(cellRect(c, cw, ch)).(*image.RGBA), bg, border, cw, ch, ww)
+			}
+		}
+		m.drawAllMarks(canvas, border, cw, ch, ww)
+		if err := fn(Frame{canvas, r, delay}); err != nil {
+			return err
+		}
+
+		lenVisited := len(visited)
+		cells := append(visited[:len(visited):len(visited)], path...)
+		for i := 0; i < len(cells); i += batch {
+			dirty := image.Rectangle{}
+			for j := i; j < i+batch && j < len(cells); j++ {
+				fill := fillVis
+				if j >= lenVisited {
+					fill = fillPath
+				}
+				rect := cellRect(cells[j], cw, ch)
+				DrawCell(cells[j], canvas.SubImage(rect).(*image.RGBA), fill, border, cw, ch, ww)
+				m.drawMarks(canvas, cells[j], image.Point{}, border, cw, ch, ww)
+				dirty = dirty.Union(rect)
+			}
+			if err := fn(Frame{canvas, dirty, delay}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// AnimatePathBatched animates visited and path cells like AnimatePath but
+// suited for big mazes: it draws batch cells per frame, every frame is
+// cropped to the area changed since previous one, and all frames share
+// minimal palette where unchanged pixels are transparent.
+// First frame is whole maze drawn with bg fill, speed in 100th of second
+func AnimatePathBatched(m *Maze, visited, path []*cell,
+	bg, fillVis, fillPath, border color.Color,
+	cw, ch, ww, speed, batch int) *gif.GIF {
+
+	enc := newGIFEncoder()
+	frames := PathFrames(m, visited, path, bg, fillVis, fillPath, border,
+		cw, ch, ww, batch, time.Duration(speed)*10*time.Millisecond)
+	// encoder never fails
+	_ = frames(enc.AddFrame)
+
+	return enc.GIF()
+}
+
+// EncodeGIF collects frames to gif animation, at most 255 distinct colors
+// are kept, others are replaced with closest one
+func EncodeGIF(frames FrameStream) (*gif.GIF, error) {
+	enc := newGIFEncoder()
+	if err := frames(enc.AddFrame); err != nil {
+		return nil, err
+	}
+	return enc.GIF(), nil
+}
+
+func cellRect(c *cell, cw, ch int) image.Rectangle {
+	return image.Rect(c.x*cw, c.y*ch, c.x*cw+cw, c.y*ch+ch)
+}
+
+// opaque returns c without alpha, nil stays nil
+func opaque(c color.Color) color.Color {
+	if c == nil {
+		return nil
+	}
+	r, g, b, _ := c.RGBA()
+	return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 255}
+}
+
+// changedRect returns bounds of pixels inside r which differ in canvas and
+// prev, with nil prev whole r is changed
+func changedRect(canvas, prev *image.RGBA, r image.Rectangle) image.Rectangle {
+	r = r.Intersect(canvas.Bounds())
+	if prev == nil {
+		return r
+	}
+	changed := image.Rectangle{}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if canvas.RGBAAt(x, y) != prev.RGBAAt(x, y) {
+				changed = changed.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return changed
+}
+
+// gifEncoder builds animation from frames, each gif frame stores only
+// pixels changed since previous frame
+type gifEncoder struct {
+	palette color.Palette
+	index   map[color.RGBA]uint8
+	prev    *image.RGBA
+	anim    *gif.GIF
+}
+
+func newGIFEncoder() *gifEncoder {
+	return &gifEncoder{
+		palette: color.Palette{color.RGBA{}},
+		index:   make(map[color.RGBA]uint8),
+		anim:    &gif.GIF{LoopCount: -1},
+	}
+}
+
+// AddFrame adds difference between frame and previous one, if nothing
+// changed delay is added to previous frame
+func (e *gifEncoder) AddFrame(f Frame) error {
+	delay := int(f.Delay / (10 * time.Millisecond))
+	changed := changedRect(f.Canvas, e.prev, f.Rect)
+	if changed.Empty() {
+		if len(e.anim.Delay) > 0 {
+			e.anim.Delay[len(e.anim.Delay)-1] += delay
+		}
+		return nil
+	}
+
+	first := e.prev == nil
+	if first {
+		e.prev = image.NewRGBA(f.Canvas.Bounds())
+		e.anim.Config.Width = f.Canvas.Bounds().Dx()
+		e.anim.Config.Height = f.Canvas.Bounds().Dy()
+	}
+	frame := image.NewPaletted(changed, nil)
+	for y := changed.Min.Y; y < changed.Max.Y; y++ {
+		for x := changed.Min.X; x < changed.Max.X; x++ {
+			c := f.Canvas.RGBAAt(x, y)
+			if !first && c == e.prev.RGBAAt(x, y) {
+				// transparent, previous frame shows through
+				frame.SetColorIndex(x, y, 0)
+				continue
+			}
+			frame.SetColorIndex(x, y, e.colorIndex(c))
+			e.prev.SetRGBA(x, y, c)
+		}
+	}
+	e.anim.Image = append(e.anim.Image, frame)
+	e.anim.Delay = append(e.anim.Delay, delay)
+	// keep frame on screen, next frames are drawn over it
+	e.anim.Disposal = append(e.anim.Disposal, gif.DisposalNone)
+
+	return nil
+}
+
+// colorIndex returns palette index of c, new colors are added to palette
+// while there is space
+func (e *gifEncoder) colorIndex(c color.RGBA) uint8 {
+	c.A = 255
+	if i, ok := e.index[c]; ok {
+		return i
+	}
+	if len(e.palette) < 256 {
+		e.index[c] = uint8(len(e.palette))
+		e.palette = append(e.palette, c)
+		return e.index[c]
+	}
+	// skip transparent color
+	return uint8(e.palette[1:].Index(c) + 1)
+}
+
+// GIF returns animation, all frames share global palette
+func (e *gifEncoder) GIF() *gif.GIF {
+	for _, frame := range e.anim.Image {
+		frame.Palette = e.palette
+	}
+	e.anim.Config.ColorModel = e.palette
+	return e.anim
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"image/gif"
+	"testing"
+)
+
+func TestAnimatePathBatched(t *testing.T) {
+	w, h := 40, 40
+	cw, ch, ww := 10, 10, 2
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 1))
+	maze.ResetVisitedCells()
+
+	path := make([]*cell, 0, w)
+	visited := make([]*cell, 0, w)
+	if !FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited) {
+		t.Fatal("path not found")
+	}
+	maze.ResetVisitedCells()
+
+	batch := 16
+	anim := AnimatePathBatched(maze, visited, path, white, yellow, red, black, cw, ch, ww, 5, batch)
+	maxFrames := 1 + (len(visited)+len(path)+batch-1)/batch
+	if len(anim.Image) > maxFrames {
+		t.Errorf("expected at most %d frames, got %d", maxFrames, len(anim.Image))
+	}
+	if len(anim.Disposal) != len(anim.Image) || len(anim.Delay) != len(anim.Image) {
+		t.Fatalf("disposal and delay should be set for every frame")
+	}
+	full := anim.Image[0].Bounds()
+	for i, frame := range anim.Image[1:] {
+		if frame.Bounds() == full {
+			t.Errorf("frame %d is not cropped", i+1)
+		}
+		if len(frame.Palette) > 5 {
+			t.Errorf("frame %d palette too big %d", i+1, len(frame.Palette))
+		}
+	}
+
+	var optimized, plain bytes.Buffer
+	if err := gif.EncodeAll(&optimized, anim); err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.EncodeAll(&plain, AnimatePath(maze, visited, path, yellow, red, black, cw, ch, ww, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if optimized.Len() >= plain.Len() {
+		t.Errorf("expected optimized gif to be smaller, got %d >= %d", optimized.Len(), plain.Len())
+	}
+	if _, err := gif.DecodeAll(&optimized); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+// breakable returns if wall between neighbour cells c and n can be broken,
+// cells across crossing of weave are not neighbours for that
+func (m *Maze) breakable(c, n *cell) bool {
+	return m.isAdjacent(c, n) && m.crossing(c, n) == nil
+}
+
+// MinWallBreaks finds path from start to end which breaks the fewest
+// walls, doors are free and each wall costs 1. It's 0-1 BFS, cells reached
+// through doors are searched before cells behind walls. Returns walls to
+// open with RmWall in order from start, path is from end to start like
+// other solvers. Masked cells can't be entered
+func MinWallBreaks(m *Maze, start, end *cell, path *[]*cell) ([][2]*cell, bool) {
+	all := func(*cell) bool { return true }
+	dist := map[*cell]int{start: 0}
+	parent := map[*cell]*cell{}
+	done := map[*cell]bool{}
+	// cells of current cost and cost + 1
+	cur, next := []*cell{start}, []*cell{}
+	for d := 0; len(cur) > 0; d++ {
+		for i := 0; i < len(cur); i++ {
+			c := cur[i]
+			// cell was reached cheaper later
+			if done[c] || dist[c] < d {
+				continue
+			}
+			done[c] = true
+			m.emit(Event{Kind: Visit, Cell: c})
+			if c == end {
+				return brokenWalls(m, start, end, parent, path), true
+			}
+			for _, n := range m.AdjacentCells(c, all) {
+				cost := 0
+				if !isConnected(c, n) {
+					if !m.breakable(c, n) {
+						continue
+					}
+					cost = 1
+				}
+				if old, ok := dist[n]; ok && old <= d+cost {
+					continue
+				}
+				dist[n], parent[n] = d+cost, c
+				if cost == 0 {
+					cur = append(cur, n)
+				} else {
+					next = append(next, n)
+				}
+			}
+		}
+		cur, next = next, cur[:0]
+	}
+	return nil, false
+}
+
+// breakState is cell reached after breaking walls
+type breakState struct {
+	c      *cell
+	breaks int
+}
+
+// FindPathWithBreaks finds the shortest path from start to end which
+// breaks at most k walls, like hammer power-up of game. It's BFS over
+// cells and breaks used so far. Returns walls broken on the way in order
+// from start, path is from end to start
+func FindPathWithBreaks(m *Maze, start, end *cell, k int, path *[]*cell) ([][2]*cell, bool) {
+	if k < 0 {
+		panic("k should be >= 0")
+	}
+	all := func(*cell) bool { return true }
+	first := breakState{start, 0}
+	parent := map[breakState]breakState{first: first}
+	q := []breakState{first}
+	for head := 0; head < len(q); head++ {
+		s := q[head]
+		m.emit(Event{Kind: Visit, Cell: s.c})
+		if s.c == end {
+			walls := [][2]*cell{}
+			for ; s != first; s = parent[s] {
+				*path = append(*path, s.c)
+				if p := parent[s]; p.breaks < s.breaks {
+					walls = append(walls, [2]*cell{p.c, s.c})
+				}
+			}
+			*path = append(*path, start)
+			m.emit(Event{Kind: Found, Cell: end, Path: *path})
+			reverseWalls(walls)
+			return walls, true
+		}
+		for _, n := range m.AdjacentCells(s.c, all) {
+			next := breakState{n, s.breaks}
+			if !isConnected(s.c, n) {
+				if s.breaks == k || !m.breakable(s.c, n) {
+					continue
+				}
+				next.breaks++
+			}
+			if _, ok := parent[next]; ok {
+				continue
+			}
+			parent[next] = s
+			q = append(q, next)
+		}
+	}
+	return nil, false
+}
+
+// brokenWalls appends path from end to start and returns walls on it in
+// order from start
+func brokenWalls(m *Maze, start, end *cell, parent map[*cell]*cell, path *[]*cell) [][2]*cell {
+	walls := [][2]*cell{}
+	for c := end; c != start; c = parent[c] {
+		*path = append(*path, c)
+		if !isConnected(parent[c], c) {
+			walls = append(walls, [2]*cell{parent[c], c})
+		}
+	}
+	*path = append(*path, start)
+	m.emit(Event{Kind: Found, Cell: end, Path: *path})
+	reverseWalls(walls)
+	return walls
+}
+
+func reverseWalls(walls [][2]*cell) {
+	for i, j := 0, len(walls)-1; i < j; i, j = i+1, j-1 {
+		walls[i], walls[j] = walls[j], walls[i]
+	}
+}
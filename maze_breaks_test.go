@@ -0,0 +1,116 @@
+package main
+
+import "testing"
+
+func TestMinWallBreaks(t *testing.T) {
+	// +---+---+---+
+	// | S     |  E|
+	// +---+---+---+
+	maze, _ := NewMaze(3, 1, point{0, 0}, point{2, 0}, nil)
+	c := maze.cells
+	maze.RmWall(c[0][0], c[1][0])
+	path := []*cell{}
+	walls, ok := MinWallBreaks(maze, maze.Begin(), maze.End(), &path)
+	if !ok || len(walls) != 1 || walls[0] != [2]*cell{c[1][0], c[2][0]} {
+		t.Fatalf("expected wall between 1 and 2, got %v", walls)
+	}
+	if len(path) != 3 || path[0] != maze.End() || path[2] != maze.Begin() {
+		t.Errorf("unexpected path %v", path)
+	}
+
+	// all walls closed, every step breaks one
+	w, h := 5, 4
+	maze, _ = NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, nil)
+	path = path[:0]
+	walls, ok = MinWallBreaks(maze, maze.Begin(), maze.End(), &path)
+	if !ok || len(walls) != w+h-2 || len(path) != w+h-1 {
+		t.Errorf("expected %d walls, got %d and path %d", w+h-2, len(walls), len(path))
+	}
+
+	// perfect maze needs no breaks
+	maze, _ = NewMaze(10, 10, point{0, 0}, point{9, 9}, DFS(NewStack(), 1))
+	path = path[:0]
+	if walls, ok = MinWallBreaks(maze, maze.Begin(), maze.End(), &path); !ok || len(walls) != 0 {
+		t.Errorf("expected no walls, got %v", walls)
+	}
+
+	// masked column can't be broken through
+	maze, _ = NewMaze(3, 2, point{0, 0}, point{2, 0}, nil)
+	maze.cells[1][0].masked = true
+	maze.cells[1][1].masked = true
+	if _, ok = MinWallBreaks(maze, maze.Begin(), maze.End(), &path); ok {
+		t.Error("expected no path through masked cells")
+	}
+}
+
+func TestRepairWithWallBreaks(t *testing.T) {
+	// maze cut in halves by wall along middle row
+	w, h := 12, 12
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 3))
+	for x := 0; x < w; x++ {
+		maze.closeDoor(maze.cells[x][h/2-1], maze.cells[x][h/2])
+	}
+	maze.ResetVisitedCells()
+	path, visited := []*cell{}, []*cell{}
+	if FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited) {
+		t.Fatal("expected disconnected maze")
+	}
+	maze.ResetVisitedCells()
+
+	path = path[:0]
+	walls, ok := MinWallBreaks(maze, maze.Begin(), maze.End(), &path)
+	// closed row may cut off more pieces of tree than two halves
+	if !ok || len(walls) == 0 {
+		t.Fatalf("expected walls to open, got %v", walls)
+	}
+	for _, wall := range walls {
+		maze.RmWall(wall[0], wall[1])
+	}
+	path, visited = path[:0], visited[:0]
+	if !FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited) {
+		t.Error("expected repaired maze")
+	}
+	maze.ResetVisitedCells()
+}
+
+func TestFindPathWithBreaks(t *testing.T) {
+	w, h := 12, 12
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 2))
+	maze.ResetVisitedCells()
+	bfs, visited := []*cell{}, []*cell{}
+	FindShortestPath(maze, maze.Begin(), maze.End(), &bfs, &visited)
+	maze.ResetVisitedCells()
+
+	last := len(bfs) + 1
+	for k := 0; k <= w+h; k++ {
+		path := []*cell{}
+		walls, ok := FindPathWithBreaks(maze, maze.Begin(), maze.End(), k, &path)
+		if !ok {
+			t.Fatalf("k=%d: path not found", k)
+		}
+		if k == 0 && (len(path) != len(bfs) || len(walls) != 0) {
+			t.Errorf("k=0: expected path of maze, got %d cells and %d walls", len(path), len(walls))
+		}
+		if len(walls) > k || len(path) > last {
+			t.Errorf("k=%d: %d walls and path %d, previous path %d", k, len(walls), len(path), last)
+		}
+		// walls are broken where path goes through them
+		broken := 0
+		for i := len(path) - 1; i > 0; i-- {
+			if !isConnected(path[i], path[i-1]) {
+				if walls[broken] != [2]*cell{path[i], path[i-1]} {
+					t.Errorf("k=%d: unexpected wall %d", k, broken)
+				}
+				broken++
+			}
+		}
+		if broken != len(walls) {
+			t.Errorf("k=%d: path breaks %d walls, reported %d", k, broken, len(walls))
+		}
+		last = len(path)
+	}
+	// enough breaks go straight
+	if last != w+h-1 {
+		t.Errorf("expected straight path of %d cells, got %d", w+h-1, last)
+	}
+}
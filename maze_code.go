@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MaxCodeCells limits size of maze decoded from code, codes come from
+// users
+const MaxCodeCells = 1 << 20
+
+// MaxCodeRooms limits rooms of dungeon decoded from code
+const MaxCodeRooms = 1000
+
+// MazeCode is everything needed to generate the same maze again. Code of
+// it looks like DFS-40x30-8f2a91 or WEAVE_0.3-20x20-1.0.19.19-ff, parts
+// are generator with parameters after _, size, optional entry and exit
+// x.y.x.y when they are not opposite corners and hex seed. Code is URL
+// safe
+type MazeCode struct {
+	Generator   string
+	Params      []float64
+	W, H        int
+	Entry, Exit point
+	Seed        int64
+}
+
+// codeGenerator is generator known to codes, kinds has letter per
+// parameter, i for integer and f for float
+type codeGenerator struct {
+	kinds string
+	gen   func(p []float64, seed int64) func(*Maze) (*Maze, []*cell)
+}
+
+var codeGenerators = map[string]codeGenerator{
+	"DFS": {"", func(p []float64, seed int64) func(*Maze) (*Maze, []*cell) {
+		return DFS(NewStack(), seed)
+	}},
+	"BTREE": {"", func(p []float64, seed int64) func(*Maze) (*Maze, []*cell) {
+		return BinaryTree(seed)
+	}},
+	"HEX": {"", func(p []float64, seed int64) func(*Maze) (*Maze, []*cell) {
+		return Hexagonal(DFS(NewStack(), seed))
+	}},
+	"TRI": {"", func(p []float64, seed int64) func(*Maze) (*Maze, []*cell) {
+		return Triangular(DFS(NewStack(), seed))
+	}},
+	"POLAR": {"", func(p []float64, seed int64) func(*Maze) (*Maze, []*cell) {
+		return Polar(DFS(NewStack(), seed))
+	}},
+	"WRAP": {"i", func(p []float64, seed int64) func(*Maze) (*Maze, []*cell) {
+		return Wrapped(Wrap(p[0]), DFS(NewStack(), seed))
+	}},
+	"LAYERED": {"i", func(p []float64, seed int64) func(*Maze) (*Maze, []*cell) {
+		return Layered(int(p[0]), DFS(NewStack(), seed))
+	}},
+	"WEAVE": {"f", func(p []float64, seed int64) func(*Maze) (*Maze, []*cell) {
+		return Weave(p[0], seed, DFS(NewStack(), seed))
+	}},
+	"KEYS": {"i", func(p []float64, seed int64) func(*Maze) (*Maze, []*cell) {
+		return Keys(int(p[0]), seed, DFS(NewStack(), seed))
+	}},
+	"TILED": {"i", func(p []float64, seed int64) func(*Maze) (*Maze, []*cell) {
+		return Tiled(int(p[0]), seed)
+	}},
+	"DUNGEON": {"iiiif", func(p []float64, seed int64) func(*Maze) (*Maze, []*cell) {
+		return Dungeon(DungeonConfig{int(p[0]), int(p[1]), int(p[2]), int(p[3]), p[4], seed})
+	}},
+}
+
+// Encode returns code of maze, it fails if generator or its parameters
+// are unknown or maze doesn't fit
+func Encode(c MazeCode) (string, error) {
+	if err := c.check(); err != nil {
+		return "", err
+	}
+	parts := []string{strings.ToUpper(c.Generator)}
+	for _, p := range c.Params {
+		parts = append(parts, strconv.FormatFloat(p, 'f', -1, 64))
+	}
+	code := []string{strings.Join(parts, "_"), fmt.Sprintf("%dx%d", c.W, c.H)}
+	if c.Entry != (point{0, 0}) || c.Exit != (point{c.W - 1, c.H - 1}) {
+		code = append(code, fmt.Sprintf("%d.%d.%d.%d", c.Entry.x, c.Entry.y, c.Exit.x, c.Exit.y))
+	}
+	code = append(code, strconv.FormatUint(uint64(c.Seed), 16))
+	return strings.Join(code, "-"), nil
+}
+
+// ParseMazeCode returns maze code parsed from code, generator name is not
+// case sensitive
+func ParseMazeCode(code string) (MazeCode, error) {
+	c := MazeCode{}
+	fields := strings.Split(code, "-")
+	if len(fields) != 3 && len(fields) != 4 {
+		return c, errors.New("code should be generator-size-seed or generator-size-entry.exit-seed")
+	}
+	parts := strings.Split(fields[0], "_")
+	c.Generator = strings.ToUpper(parts[0])
+	for _, s := range parts[1:] {
+		p, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return c, fmt.Errorf("bad parameter %q", s)
+		}
+		c.Params = append(c.Params, p)
+	}
+	if _, err := fmt.Sscanf(fields[1], "%dx%d", &c.W, &c.H); err != nil || fmt.Sprintf("%dx%d", c.W, c.H) != fields[1] {
+		return c, fmt.Errorf("bad size %q", fields[1])
+	}
+	c.Exit = point{c.W - 1, c.H - 1}
+	if len(fields) == 4 {
+		ends := strings.Split(fields[2], ".")
+		n := []int{}
+		for _, s := range ends {
+			i, err := strconv.Atoi(s)
+			if err != nil {
+				break
+			}
+			n = append(n, i)
+		}
+		if len(ends) != 4 || len(n) != 4 {
+			return c, fmt.Errorf("bad entry and exit %q", fields[2])
+		}
+		c.Entry, c.Exit = point{n[0], n[1]}, point{n[2], n[3]}
+	}
+	seed, err := strconv.ParseUint(fields[len(fields)-1], 16, 64)
+	if err != nil {
+		return c, fmt.Errorf("bad seed %q", fields[len(fields)-1])
+	}
+	c.Seed = int64(seed)
+	return c, c.check()
+}
+
+// check returns error if maze of code can't be generated
+func (c MazeCode) check() error {
+	g, ok := codeGenerators[strings.ToUpper(c.Generator)]
+	if !ok {
+		return fmt.Errorf("unknown generator %q", c.Generator)
+	}
+	if len(c.Params) != len(g.kinds) {
+		return fmt.Errorf("generator %s takes %d parameters", c.Generator, len(g.kinds))
+	}
+	for i, p := range c.Params {
+		// minus separates parts of code
+		if p < 0 || math.IsInf(p, 0) || math.IsNaN(p) || g.kinds[i] == 'i' && p != math.Trunc(p) {
+			return fmt.Errorf("bad parameter %v", p)
+		}
+	}
+	if c.W < 1 || c.H < 1 || c.W*c.H > MaxCodeCells || c.W > MaxCodeCells || c.H > MaxCodeCells {
+		return fmt.Errorf("bad size %dx%d", c.W, c.H)
+	}
+	for i, limit := range c.maxParams() {
+		if c.Params[i] > limit {
+			return fmt.Errorf("parameter %v of %s is over %v", c.Params[i], strings.ToUpper(c.Generator), limit)
+		}
+	}
+	for _, p := range []point{c.Entry, c.Exit} {
+		if p.x < 0 || p.x >= c.W || p.y < 0 || p.y >= c.H {
+			return fmt.Errorf("point %d.%d outside of maze", p.x, p.y)
+		}
+	}
+	return nil
+}
+
+// maxParams returns the largest parameters of generator for maze size,
+// generators given too many rooms or keys would run for hours or allocate
+// gigabytes
+func (c MazeCode) maxParams() []float64 {
+	side := c.W
+	if c.H > side {
+		side = c.H
+	}
+	switch strings.ToUpper(c.Generator) {
+	case "WRAP":
+		return []float64{float64(WrapTorus)}
+	case "LAYERED":
+		return []float64{float64(c.H)}
+	case "WEAVE":
+		return []float64{1}
+	case "KEYS":
+		return []float64{MaxKeys}
+	case "TILED":
+		return []float64{float64(side)}
+	case "DUNGEON":
+		// rooms which fit with gaps between them, placing of rooms is
+		// quadratic so there is at most MaxCodeRooms of them
+		gap := c.Params[1] + 1
+		rooms := math.Min(MaxCodeRooms, math.Floor(float64(c.W*c.H)/(gap*gap)))
+		// rooms, min and max size, doors, prune
+		return []float64{rooms, float64(side), float64(side), 4, 1}
+	}
+	return nil
+}
+
+// Generate returns maze of code, generators panic on parameters they
+// can't use, such panic is returned as error
+func (c MazeCode) Generate() (m *Maze, genPath []*cell, err error) {
+	if err := c.check(); err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			m, genPath, err = nil, nil, fmt.Errorf("can't generate maze: %v", r)
+		}
+	}()
+	g := codeGenerators[strings.ToUpper(c.Generator)]
+	m, genPath = NewMaze(c.W, c.H, c.Entry, c.Exit, g.gen(c.Params, c.Seed))
+	return m, genPath, nil
+}
+
+// Decode returns maze generated from code
+func Decode(code string) (*Maze, []*cell, error) {
+	c, err := ParseMazeCode(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.Generate()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	m, _, err := Decode("DFS-40x30-8f2a91")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := NewMaze(40, 30, point{0, 0}, point{39, 29}, DFS(NewStack(), 0x8f2a91))
+	if m.String() != expected.String() {
+		t.Error("decoded maze differs from generated one")
+	}
+	again, _, _ := Decode("dfs-40x30-8f2a91")
+	if again.String() != m.String() {
+		t.Error("expected same maze for same code")
+	}
+	other, _, _ := Decode("DFS-40x30-8f2a92")
+	if other.String() == m.String() {
+		t.Error("expected other maze for other seed")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, tc := range []struct {
+		c    MazeCode
+		code string
+	}{
+		{MazeCode{"DFS", nil, 40, 30, point{0, 0}, point{39, 29}, 0x8f2a91}, "DFS-40x30-8f2a91"},
+		{MazeCode{"weave", []float64{0.3}, 20, 20, point{1, 0}, point{19, 19}, 255}, "WEAVE_0.3-20x20-1.0.19.19-ff"},
+		{MazeCode{"DUNGEON", []float64{5, 3, 5, 2, 0.5}, 30, 20, point{0, 0}, point{29, 19}, -1},
+			"DUNGEON_5_3_5_2_0.5-30x20-ffffffffffffffff"},
+		{MazeCode{"TILED", []float64{4}, 12, 8, point{0, 0}, point{11, 7}, 1}, "TILED_4-12x8-1"},
+		{MazeCode{"POLAR", nil, PolarWidth(5), 5, point{0, 0}, point{0, 4}, 7}, fmt.Sprintf("POLAR-%dx5-0.0.0.4-7", PolarWidth(5))},
+	} {
+		code, err := Encode(tc.c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tc.code {
+			t.Errorf("expected %s, got %s", tc.code, code)
+		}
+		c, err := ParseMazeCode(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Seed != tc.c.Seed || c.Entry != tc.c.Entry || c.Exit != tc.c.Exit || len(c.Params) != len(tc.c.Params) {
+			t.Errorf("unexpected parsed code %v of %s", c, code)
+		}
+
+		m, _, err := Decode(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, _, _ := tc.c.Generate()
+		if m.String() != expected.String() {
+			t.Errorf("decoded maze of %s differs", code)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, code := range []string{
+		"",
+		"DFS-40x30",
+		"BFS-40x30-1",
+		"DFS_1-40x30-1",
+		"WEAVE-40x30-1",
+		"KEYS_1.5-40x30-1",
+		"DFS-40y30-1",
+		"DFS-0x30-1",
+		"DFS-100000x100000-1",
+		"DFS-40x30-0.0.40.0-1",
+		"DFS-40x30-0.0.1-1",
+		"DFS-40x30-xyz",
+		// parameters over limits
+		"DUNGEON_30000000_1_1_1_0-8x8-1",
+		"DUNGEON_10000_1_1_1_0-200x200-1",
+		"DUNGEON_17_1_1_1_0-8x8-1",
+		"DUNGEON_4_9_9_1_0-8x8-1",
+		"DUNGEON_4_1_9_1_0-8x8-1",
+		"DUNGEON_4_1_3_5_0-8x8-1",
+		"DUNGEON_4_1_3_1_1.5-8x8-1",
+		"WEAVE_1.01-40x30-1",
+		"WRAP_3-40x30-1",
+		"KEYS_1000000-40x30-1",
+		"TILED_41-40x30-1",
+		"LAYERED_31-40x30-1",
+		// panic of generator
+		"LAYERED_4-10x10-1",
+		"TILED_0-10x10-1",
+	} {
+		if _, _, err := Decode(code); err == nil {
+			t.Errorf("expected error for %q", code)
+		}
+	}
+	if _, err := Encode(MazeCode{Generator: "WEAVE", Params: []float64{-1}, W: 2, H: 2, Exit: point{1, 1}}); err == nil {
+		t.Error("expected error for negative parameter")
+	}
+}
+
+func TestDecodeWorstDungeon(t *testing.T) {
+	// most rooms code allows, each try of placing checks every room
+	start := time.Now()
+	if _, _, err := Decode(fmt.Sprintf("DUNGEON_%d_1_1_1_0-100x100-1", MaxCodeRooms)); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected dungeon decoded in seconds, took %v", d)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Limits bound work of solvers and generators, zero means no limit. Step
+// is any event but found, see EventKind. MaxCells bounds memory, it's
+// the largest maze generator may allocate and the most cells solver may
+// keep as visited
+type Limits struct {
+	MaxSteps int
+	MaxCells int
+}
+
+var (
+	ErrStepLimit = errors.New("step limit reached")
+	ErrCellLimit = errors.New("cell limit reached")
+)
+
+// StopError is returned when solver or generator is stopped before it's
+// done, results are partial. Cause is ctx.Err(), ErrStepLimit or
+// ErrCellLimit, errors.Is works with them
+type StopError struct {
+	Cause error
+	Steps int
+}
+
+func (e *StopError) Error() string {
+	return fmt.Sprintf("stopped after %d steps: %v", e.Steps, e.Cause)
+}
+
+func (e *StopError) Unwrap() error {
+	return e.Cause
+}
+
+// control stops solver or generator of maze, it's checked on every step.
+// Tiled generation steps from several goroutines
+type control struct {
+	ctx     context.Context
+	limits  Limits
+	visited *[]*cell
+	steps   int64
+	stopped int32
+	err     atomic.Value
+}
+
+// step counts step and returns false when work should stop
+func (c *control) step() bool {
+	if atomic.LoadInt32(&c.stopped) != 0 {
+		return false
+	}
+	steps := atomic.AddInt64(&c.steps, 1)
+	var cause error
+	switch {
+	case c.ctx.Err() != nil:
+		cause = c.ctx.Err()
+	case c.limits.MaxSteps > 0 && steps > int64(c.limits.MaxSteps):
+		cause = ErrStepLimit
+	case c.limits.MaxCells > 0 && c.visited != nil && len(*c.visited) > c.limits.MaxCells:
+		cause = ErrCellLimit
+	}
+	if cause == nil {
+		return true
+	}
+	if atomic.CompareAndSwapInt32(&c.stopped, 0, 1) {
+		c.err.Store(&StopError{cause, int(steps - 1)})
+	}
+	return false
+}
+
+// error returns why control stopped, nil if it didn't
+func (c *control) error() error {
+	if err, ok := c.err.Load().(*StopError); ok {
+		return err
+	}
+	return nil
+}
+
+// halted returns if solver or generator should return now
+func (m *Maze) halted() bool {
+	return m.ctl != nil && atomic.LoadInt32(&m.ctl.stopped) != 0
+}
+
+// step counts step of control without event
+func (m *Maze) step() {
+	if m.ctl != nil {
+		m.ctl.step()
+	}
+}
+
+// NewMazeContext is NewMaze which stops generator on cancel of ctx or when
+// limits are reached. Maze bigger than MaxCells is not allocated at all.
+// Stopped generator returns partially carved maze with *StopError
+func NewMazeContext(ctx context.Context, w, h int, entry, exit point,
+	generator func(*Maze) (*Maze, []*cell), limits Limits) (m *Maze, genPath []*cell, err error) {
+
+	if limits.MaxCells > 0 && (w > limits.MaxCells || h > limits.MaxCells || w*h > limits.MaxCells) {
+		return nil, nil, &StopError{ErrCellLimit, 0}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, &StopError{err, 0}
+	}
+	ctl := &control{ctx: ctx, limits: limits}
+	defer func() {
+		// generators may panic on maze left unfinished
+		if r := recover(); r != nil {
+			if ctl.error() == nil {
+				panic(r)
+			}
+			genPath = nil
+		}
+		if m != nil {
+			m.ctl = nil
+		}
+		err = ctl.error()
+	}()
+	m, genPath = NewMaze(w, h, entry, exit, func(maze *Maze) (*Maze, []*cell) {
+		m, maze.ctl = maze, ctl
+		if generator == nil {
+			return maze, nil
+		}
+		return generator(maze)
+	})
+	return m, genPath, nil
+}
+
+// SolveContext runs solver which stops on cancel of ctx or when limits are
+// reached. Stopped solver returns false with *StopError, visited has cells
+// it got to and path of DFS solver has its current branch
+func SolveContext(ctx context.Context, solver func(m *Maze, start, end *cell, path, visited *[]*cell) bool,
+	m *Maze, start, end *cell, path, visited *[]*cell, limits Limits) (bool, error) {
+
+	if err := ctx.Err(); err != nil {
+		return false, &StopError{err, 0}
+	}
+	m.ctl = &control{ctx: ctx, limits: limits, visited: visited}
+	defer func() { m.ctl = nil }()
+	// path may be complete even if limit is hit on last step
+	if solver(m, start, end, path, visited) {
+		return true, nil
+	}
+	return false, m.ctl.error()
+}
+
+// FindPathContext is FindPath which can be stopped, see SolveContext
+func FindPathContext(ctx context.Context, m *Maze, start, end *cell, path, visited *[]*cell, limits Limits) (bool, error) {
+	return SolveContext(ctx, FindPath, m, start, end, path, visited, limits)
+}
+
+// FindShortestPathContext is FindShortestPath which can be stopped, see
+// SolveContext
+func FindShortestPathContext(ctx context.Context, m *Maze, start, end *cell, path, visited *[]*cell, limits Limits) (bool, error) {
+	return SolveContext(ctx, FindShortestPath, m, start, end, path, visited, limits)
+}
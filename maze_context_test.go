@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSolveContext(t *testing.T) {
+	w, h := 30, 30
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 1))
+	maze.ResetVisitedCells()
+	ctx := context.Background()
+
+	path, visited := []*cell{}, []*cell{}
+	found, err := FindShortestPathContext(ctx, maze, maze.Begin(), maze.End(), &path, &visited, Limits{MaxSteps: 10})
+	maze.ResetVisitedCells()
+	stop := &StopError{}
+	if found || !errors.Is(err, ErrStepLimit) || !errors.As(err, &stop) || stop.Steps != 10 {
+		t.Fatalf("expected step limit, got %v %v", found, err)
+	}
+	if len(visited) == 0 || len(visited) > 10 || len(path) != 0 {
+		t.Errorf("expected partial visited cells, got %d visited and path %d", len(visited), len(path))
+	}
+
+	path, visited = []*cell{}, []*cell{}
+	_, err = FindShortestPathContext(ctx, maze, maze.Begin(), maze.End(), &path, &visited, Limits{MaxCells: 50})
+	maze.ResetVisitedCells()
+	if !errors.Is(err, ErrCellLimit) || len(visited) > 51+4 {
+		t.Errorf("expected cell limit, got %v with %d visited", err, len(visited))
+	}
+
+	// DFS keeps current branch
+	path, visited = []*cell{}, []*cell{}
+	_, err = FindPathContext(ctx, maze, maze.Begin(), maze.End(), &path, &visited, Limits{MaxSteps: 100})
+	maze.ResetVisitedCells()
+	if !errors.Is(err, ErrStepLimit) || len(path) == 0 || path[0] != maze.Begin() {
+		t.Fatalf("expected branch from begin, got %v of %d cells", err, len(path))
+	}
+	for i := 1; i < len(path); i++ {
+		if !isConnected(path[i-1], path[i]) {
+			t.Errorf("branch is broken at %v", path[i].point)
+		}
+	}
+
+	// limits are not kept
+	path, visited = []*cell{}, []*cell{}
+	if found, err := FindShortestPathContext(ctx, maze, maze.Begin(), maze.End(), &path, &visited, Limits{MaxSteps: 10000}); !found || err != nil {
+		t.Errorf("expected path, got %v", err)
+	}
+	maze.ResetVisitedCells()
+	path, visited = []*cell{}, []*cell{}
+	if !FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited) {
+		t.Error("expected path without limits")
+	}
+	maze.ResetVisitedCells()
+}
+
+func TestSolveContextCancel(t *testing.T) {
+	w, h := 30, 30
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 2))
+	maze.ResetVisitedCells()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	path, visited := []*cell{}, []*cell{}
+	if _, err := FindShortestPathContext(ctx, maze, maze.Begin(), maze.End(), &path, &visited, Limits{}); !errors.Is(err, context.Canceled) || len(visited) != 0 {
+		t.Errorf("expected canceled search, got %v", err)
+	}
+
+	// cancel in the middle of search
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	visits := 0
+	maze.Observe(func(e Event) {
+		if e.Kind == Visit {
+			if visits++; visits == 20 {
+				cancel()
+			}
+		}
+	})
+	_, err := SolveContext(ctx, FindShortestPathAStar, maze, maze.Begin(), maze.End(), &path, &visited, Limits{})
+	maze.ResetVisitedCells()
+	if !errors.Is(err, context.Canceled) || len(visited) != 20 {
+		t.Errorf("expected search stopped after 20 visits, got %v with %d visited", err, len(visited))
+	}
+}
+
+func TestNewMazeContext(t *testing.T) {
+	ctx := context.Background()
+	if m, _, err := NewMazeContext(ctx, 1000, 1000, point{0, 0}, point{0, 0}, DFS(NewStack(), 1), Limits{MaxCells: 10000}); m != nil || !errors.Is(err, ErrCellLimit) {
+		t.Errorf("expected maze over limit not allocated, got %v", err)
+	}
+
+	generators := map[string]func(*Maze) (*Maze, []*cell){
+		"dfs":     DFS(NewStack(), 1),
+		"btree":   BinaryTree(1),
+		"tiled":   Tiled(5, 1),
+		"keys":    Keys(2, 1, DFS(NewStack(), 1)),
+		"dungeon": Dungeon(DungeonConfig{Rooms: 4, MinSize: 3, MaxSize: 5, Doors: 2, Seed: 1}),
+	}
+	for name, gen := range generators {
+		carved := 0
+		m, _, err := NewMazeContext(ctx, 20, 20, point{0, 0}, point{19, 19},
+			Observed(func(e Event) {
+				if e.Kind == Carve {
+					carved++
+				}
+			}, gen), Limits{MaxSteps: 50})
+		if m == nil || !errors.Is(err, ErrStepLimit) {
+			t.Errorf("%s: expected partial maze, got %v", name, err)
+			continue
+		}
+		if carved > 51 {
+			t.Errorf("%s: expected at most 51 carved walls, got %d", name, carved)
+		}
+		if m.ctl != nil {
+			t.Errorf("%s: control is kept", name)
+		}
+	}
+
+	m, _, err := NewMazeContext(ctx, 20, 20, point{0, 0}, point{19, 19}, DFS(NewStack(), 1), Limits{MaxSteps: 1000, MaxCells: 400})
+	expected, _ := NewMaze(20, 20, point{0, 0}, point{19, 19}, DFS(NewStack(), 1))
+	if err != nil || m.String() != expected.String() {
+		t.Errorf("expected whole maze within limits, got %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"image"
+	"math/rand"
+)
+
+// DungeonConfig describes rooms of dungeon, rooms are squares or rectangles
+// with sides from MinSize to MaxSize cells. Each room gets from 1 to Doors
+// doors, Prune is chance to remove each dead end of corridors, 1 removes
+// all of them, 0 keeps perfect maze between rooms
+type DungeonConfig struct {
+	Rooms            int
+	MinSize, MaxSize int
+	Doors            int
+	Prune            float64
+	Seed             int64
+}
+
+// Dungeon returns generator of rooms connected by corridors. Rooms are
+// open regions placed so they don't overlap or touch, space between them
+// is filled with DFS maze, then rooms are connected to corridors through
+// doors and dead ends of corridors are pruned. Pruned cells are masked
+func Dungeon(cfg DungeonConfig) func(*Maze) (*Maze, []*cell) {
+	return func(m *Maze) (*Maze, []*cell) {
+		if _, ok := m.topology.(squareGrid); !ok {
+			panic("dungeon needs square grid")
+		}
+		if cfg.MinSize < 1 || cfg.MaxSize < cfg.MinSize || cfg.Doors < 1 {
+			panic("room size and doors should be positive")
+		}
+		r := rand.New(rand.NewSource(cfg.Seed))
+		genPath := make([]*cell, 0, m.w*m.h)
+		// cells of same region are connected, rooms are first regions
+		region := map[*cell]int{}
+
+		rooms := m.placeRooms(r, cfg)
+		for i, room := range rooms {
+			for x := room.Min.X; x < room.Max.X; x++ {
+				for y := room.Min.Y; y < room.Max.Y; y++ {
+					if m.halted() {
+						return m, genPath
+					}
+					c := m.cells[x][y]
+					region[c] = i
+					genPath = append(genPath, c)
+					if x > room.Min.X {
+						m.RmWall(m.cells[x-1][y], c)
+					}
+					if y > room.Min.Y {
+						m.RmWall(m.cells[x][y-1], c)
+					}
+				}
+			}
+		}
+
+		// corridors in the rest of grid
+		n := len(rooms)
+		for x := range m.cells {
+			for _, c := range m.cells[x] {
+				if _, ok := region[c]; ok || c.masked {
+					continue
+				}
+				genPath = append(genPath, m.carve(r, c, region, n)...)
+				n++
+			}
+		}
+
+		if m.halted() {
+			return m, genPath
+		}
+		m.connectRegions(r, rooms, region, n, cfg.Doors)
+		m.pruneDeadEnds(r, region, len(rooms), cfg.Prune)
+		for x := range m.cells {
+			for _, c := range m.cells[x] {
+				c.visited = !c.masked
+			}
+		}
+		return m, genPath
+	}
+}
+
+// maxRoomTries limits tries of placeRooms, each try checks all rooms
+// placed before
+const maxRoomTries = 20000
+
+// placeRooms returns rooms which don't overlap, touch or cover masked
+// cells, it gives up after number of failed tries
+func (m *Maze) placeRooms(r *rand.Rand, cfg DungeonConfig) []image.Rectangle {
+	rooms := []image.Rectangle{}
+	for try := 0; len(rooms) < cfg.Rooms && try < 20*cfg.Rooms && try < maxRoomTries; try++ {
+		w := cfg.MinSize + r.Intn(cfg.MaxSize-cfg.MinSize+1)
+		h := cfg.MinSize + r.Intn(cfg.MaxSize-cfg.MinSize+1)
+		if w > m.w || h > m.h {
+			continue
+		}
+		x, y := r.Intn(m.w-w+1), r.Intn(m.h-h+1)
+		room := image.Rect(x, y, x+w, y+h)
+		free := true
+		for _, other := range rooms {
+			// keep gap for corridor
+			if room.Inset(-1).Overlaps(other) {
+				free = false
+				break
+			}
+		}
+		for x := room.Min.X; free && x < room.Max.X; x++ {
+			for y := room.Min.Y; y < room.Max.Y; y++ {
+				if m.cells[x][y].masked {
+					free = false
+					break
+				}
+			}
+		}
+		if free {
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms
+}
+
+// carve makes DFS maze of cells not in any region starting from c, cells
+// get region id. Returns carved cells in order
+func (m *Maze) carve(r *rand.Rand, c *cell, region map[*cell]int, id int) []*cell {
+	carved := []*cell{c}
+	region[c] = id
+	stack := NewStack()
+	filter := func(n *cell) bool {
+		_, ok := region[n]
+		return !ok
+	}
+	for current := c; !m.halted(); {
+		free := m.AdjacentCells(current, filter)
+		if len(free) > 0 {
+			next := free[r.Intn(len(free))]
+			stack.Push(current)
+			m.RmWall(current, next)
+			region[next] = id
+			carved = append(carved, next)
+			current = next
+		} else if stack.Len() > 0 {
+			current = stack.Pop()
+		} else {
+			break
+		}
+	}
+	return carved
+}
+
+// connectRegions opens walls between regions until they are all joined,
+// then adds extra doors so each room has 1 to doors doors
+func (m *Maze) connectRegions(r *rand.Rand, rooms []image.Rectangle, region map[*cell]int, n, doors int) {
+	// walls between different regions
+	connectors := [][2]*cell{}
+	for x := range m.cells {
+		for _, c := range m.cells[x] {
+			if c.masked {
+				continue
+			}
+			for _, next := range m.AdjacentCells(c, func(*cell) bool { return true }) {
+				if region[c] < region[next] && !isConnected(c, next) {
+					connectors = append(connectors, [2]*cell{c, next})
+				}
+			}
+		}
+	}
+	r.Shuffle(len(connectors), func(i, j int) {
+		connectors[i], connectors[j] = connectors[j], connectors[i]
+	})
+
+	// union find of regions
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	opened := make([]int, len(rooms))
+	open := func(w [2]*cell) {
+		m.RmWall(w[0], w[1])
+		for _, c := range w {
+			if region[c] < len(rooms) {
+				opened[region[c]]++
+			}
+		}
+	}
+	rest := connectors[:0]
+	for _, w := range connectors {
+		if a, b := find(region[w[0]]), find(region[w[1]]); a != b {
+			parent[a] = b
+			open(w)
+		} else {
+			rest = append(rest, w)
+		}
+	}
+
+	// extra doors of rooms
+	want := make([]int, len(rooms))
+	for i := range want {
+		want[i] = 1 + r.Intn(doors)
+	}
+	for _, w := range rest {
+		room := region[w[0]]
+		if room >= len(rooms) {
+			room = region[w[1]]
+		}
+		if room < len(rooms) && opened[room] < want[room] {
+			open(w)
+		}
+	}
+}
+
+// pruneDeadEnds masks corridor cells with one door, each dead end is
+// removed with chance p and then cell it led to is checked again.
+// Rooms, begin and end are kept
+func (m *Maze) pruneDeadEnds(r *rand.Rand, region map[*cell]int, rooms int, p float64) {
+	doors := func(c *cell) []*cell {
+		return m.AdjacentCells(c, func(n *cell) bool {
+			return isConnected(c, n)
+		})
+	}
+	q := []*cell{}
+	for x := range m.cells {
+		for _, c := range m.cells[x] {
+			q = append(q, c)
+		}
+	}
+	for len(q) > 0 {
+		c := q[0]
+		q = q[1:]
+		if c.masked || region[c] < rooms || c == m.Begin() || c == m.End() {
+			continue
+		}
+		open := doors(c)
+		if len(open) > 1 || r.Float64() >= p {
+			continue
+		}
+		for _, n := range open {
+			m.closeDoor(c, n)
+			q = append(q, n)
+		}
+		c.masked = true
+	}
+}
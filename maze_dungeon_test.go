@@ -0,0 +1,73 @@
+package main
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestDungeon(t *testing.T) {
+	w, h := 30, 20
+	cfg := DungeonConfig{Rooms: 6, MinSize: 3, MaxSize: 5, Doors: 2, Prune: 1, Seed: 1}
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, Dungeon(cfg))
+	maze.ResetVisitedCells()
+
+	rooms := maze.placeRooms(rand.New(rand.NewSource(cfg.Seed)), cfg)
+	if len(rooms) == 0 {
+		t.Fatal("expected rooms")
+	}
+	for _, room := range rooms {
+		for x := room.Min.X; x < room.Max.X; x++ {
+			for y := room.Min.Y; y < room.Max.Y; y++ {
+				c := maze.cells[x][y]
+				if c.masked ||
+					x > room.Min.X && !c.left || x < room.Max.X-1 && !c.right ||
+					y > room.Min.Y && !c.up || y < room.Max.Y-1 && !c.down {
+					t.Fatalf("room %v is not open at %v", room, c.point)
+				}
+			}
+		}
+	}
+
+	// all cells left are reachable, no dead ends in corridors
+	reached, _ := maze.reach([]*cell{maze.Begin()}, 0)
+	for x := range maze.cells {
+		for _, c := range maze.cells[x] {
+			if c.masked {
+				continue
+			}
+			if _, ok := reached[c]; !ok {
+				t.Errorf("cell %v not reachable", c.point)
+			}
+			inRoom := false
+			for _, room := range rooms {
+				inRoom = inRoom || image.Pt(c.x, c.y).In(room)
+			}
+			doors := maze.AdjacentCells(c, func(n *cell) bool { return isConnected(c, n) })
+			if !inRoom && len(doors) < 2 && c != maze.Begin() && c != maze.End() {
+				t.Errorf("dead end at %v", c.point)
+			}
+		}
+	}
+
+	again, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, Dungeon(cfg))
+	if again.String() != maze.String() {
+		t.Error("expected same dungeon for same seed")
+	}
+
+	path := make([]*cell, 0, w)
+	visited := make([]*cell, 0, w)
+	if !FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited) {
+		t.Error("path not found")
+	}
+}
+
+func TestDungeonRoomTries(t *testing.T) {
+	// tries are limited however many rooms are asked for
+	start := time.Now()
+	NewMaze(100, 100, point{0, 0}, point{99, 99}, Dungeon(DungeonConfig{Rooms: 1e6, MinSize: 1, MaxSize: 1, Doors: 1, Seed: 1}))
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected dungeon generated in seconds, took %v", d)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventKind is what happened to cell during search or generation
+type EventKind int
+
+const (
+	Visit     EventKind = iota // solver expands cell
+	Enqueue                    // solver puts cell to queue, From is its parent
+	Backtrack                  // search or generation leaves dead end cell
+	Found                      // solver reached end, Path is the result
+	Carve                      // wall between From and Cell is removed
+)
+
+var eventNames = [...]string{"visit", "enqueue", "backtrack", "found", "carve"}
+
+func (k EventKind) String() string {
+	if k < 0 || int(k) >= len(eventNames) {
+		return fmt.Sprintf("event%d", int(k))
+	}
+	return eventNames[k]
+}
+
+// Event is step of solver or generator. Path is owned by solver, it's
+// valid only until observer returns
+type Event struct {
+	Kind EventKind
+	Cell *cell
+	From *cell
+	Path []*cell
+	m    *Maze
+}
+
+// Observer is called for every event of maze in order
+type Observer func(Event)
+
+// Observe makes solvers and generators of maze emit events to obs, nil
+// stops them. Events are emitted from one goroutine at a time, when no
+// other goroutine changes maze, so obs may read whole maze. Tiled carves
+// tiles concurrently and emits their carves after all tiles are done.
+// Solver waits for obs to return
+func (m *Maze) Observe(obs Observer) {
+	if obs == nil {
+		m.observer = nil
+		return
+	}
+	mu := &sync.Mutex{}
+	m.observer = func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		obs(e)
+	}
+}
+
+// Observed returns generator which emits events of generator to obs, maze
+// keeps obs for solvers afterwards
+func Observed(obs Observer, generator func(*Maze) (*Maze, []*cell)) func(*Maze) (*Maze, []*cell) {
+	return func(m *Maze) (*Maze, []*cell) {
+		m.Observe(obs)
+		return generator(m)
+	}
+}
+
+// emit passes event to observer and counts step of control
+func (m *Maze) emit(e Event) {
+	if m.observer != nil {
+		e.m = m
+		m.observer(e)
+	}
+	if e.Kind != Found {
+		m.step()
+	}
+}
+
+// Broadcast returns observer which passes events to every observer
+func Broadcast(observers ...Observer) Observer {
+	return func(e Event) {
+		for _, obs := range observers {
+			obs(e)
+		}
+	}
+}
+
+// GIFSink draws events to gif animation like PathFrames, batch events per
+// frame. First frame is maze as it was at first event. Only square mazes
+// are drawn
+type GIFSink struct {
+	bg, fillVis, fillQueue, fillPath, border color.Color
+	cw, ch, ww, batch                        int
+	delay                                    time.Duration
+
+	m      *Maze
+	canvas *image.RGBA
+	dirty  image.Rectangle
+	n      int
+	enc    *gifEncoder
+}
+
+// NewGIFSink returns sink drawing visited, queued and path cells with own
+// fills, carved and backtracked cells with bg
+func NewGIFSink(bg, fillVis, fillQueue, fillPath, border color.Color,
+	cw, ch, ww, batch int, delay time.Duration) *GIFSink {
+
+	if batch < 1 {
+		batch = 1
+	}
+	return &GIFSink{
+		bg: opaque(bg), fillVis: opaque(fillVis), fillQueue: opaque(fillQueue),
+		fillPath: opaque(fillPath), border: opaque(border),
+		cw: cw, ch: ch, ww: ww, batch: batch, delay: delay,
+		enc: newGIFEncoder(),
+	}
+}
+
+// Observe draws event, it's Observer of sink
+func (s *GIFSink) Observe(e Event) {
+	if s.canvas == nil {
+		s.m = e.m
+		s.canvas = image.NewRGBA(image.Rect(0, 0, s.m.w*s.cw, s.m.h*s.ch))
+		for x := range s.m.cells {
+			for _, c := range s.m.cells[x] {
+				s.draw(c, s.bg)
+			}
+		}
+		s.m.drawAllMarks(s.canvas, s.border, s.cw, s.ch, s.ww)
+		s.frame()
+	}
+	switch e.Kind {
+	case Visit:
+		s.draw(e.Cell, s.fillVis)
+	case Enqueue:
+		s.draw(e.Cell, s.fillQueue)
+	case Backtrack:
+		s.draw(e.Cell, s.bg)
+	case Found:
+		for _, c := range e.Path {
+			s.draw(c, s.fillPath)
+		}
+	case Carve:
+		s.draw(e.From, s.bg)
+		s.draw(e.Cell, s.bg)
+	}
+	if s.n++; s.n%s.batch == 0 || e.Kind == Found {
+		s.frame()
+	}
+}
+
+func (s *GIFSink) draw(c *cell, fill color.Color) {
+	rect := cellRect(c, s.cw, s.ch)
+	DrawCell(c, s.canvas.SubImage(rect).(*image.RGBA), fill, s.border, s.cw, s.ch, s.ww)
+	s.m.drawMarks(s.canvas, c, image.Point{}, s.border, s.cw, s.ch, s.ww)
+	s.dirty = s.dirty.Union(rect)
+}
+
+func (s *GIFSink) frame() {
+	// encoder never fails
+	_ = s.enc.AddFrame(Frame{s.canvas, s.dirty, s.delay})
+	s.dirty = image.Rectangle{}
+}
+
+// GIF returns animation of events so far
+func (s *GIFSink) GIF() *gif.GIF {
+	if !s.dirty.Empty() {
+		s.frame()
+	}
+	return s.enc.GIF()
+}
+
+// TerminalSink redraws maze as text with visited cells . queued o and path
+// * every n events, it's for watching long solves in terminal
+type TerminalSink struct {
+	w     io.Writer
+	every int
+	n     int
+	m     *Maze
+	marks map[*cell]byte
+	err   error
+}
+
+// NewTerminalSink returns sink writing to w every n events and at the end
+// of search
+func NewTerminalSink(w io.Writer, every int) *TerminalSink {
+	if every < 1 {
+		every = 1
+	}
+	return &TerminalSink{w: w, every: every, marks: map[*cell]byte{}}
+}
+
+// Observe records event, it's Observer of sink
+func (s *TerminalSink) Observe(e Event) {
+	s.m = e.m
+	switch e.Kind {
+	case Visit:
+		s.marks[e.Cell] = '.'
+	case Enqueue:
+		s.marks[e.Cell] = 'o'
+	case Backtrack:
+		delete(s.marks, e.Cell)
+	case Found:
+		for _, c := range e.Path {
+			s.marks[c] = '*'
+		}
+	}
+	if s.n++; s.n%s.every == 0 || e.Kind == Found {
+		s.show()
+	}
+}
+
+// show clears terminal and writes maze with marks and count of events
+func (s *TerminalSink) show() {
+	if s.err != nil {
+		return
+	}
+	lines := strings.Split(s.m.String(), "\n")
+	for c, mark := range s.marks {
+		if c == s.m.Begin() || c == s.m.End() || 2*c.y+1 >= len(lines) {
+			continue
+		}
+		line := []byte(lines[2*c.y+1])
+		line[4*c.x+2] = mark
+		lines[2*c.y+1] = string(line)
+	}
+	_, s.err = fmt.Fprintf(s.w, "\x1b[H\x1b[2J%sevents %d\n", strings.Join(lines, "\n"), s.n)
+}
+
+// Err returns first error of writing to terminal
+func (s *TerminalSink) Err() error {
+	return s.err
+}
+
+// EventHub streams events to HTTP clients as server sent events, each
+// event is JSON like {"kind":"visit","x":1,"y":2}. Clients which can't
+// keep up lose events, solver never waits for them
+type EventHub struct {
+	mu      sync.Mutex
+	clients map[chan []byte]bool
+	buffer  int
+}
+
+// NewEventHub returns hub keeping up to buffer events per client
+func NewEventHub(buffer int) *EventHub {
+	return &EventHub{clients: map[chan []byte]bool{}, buffer: buffer}
+}
+
+type eventJSON struct {
+	Kind string   `json:"kind"`
+	X    int      `json:"x"`
+	Y    int      `json:"y"`
+	From []int    `json:"from,omitempty"`
+	Path [][2]int `json:"path,omitempty"`
+}
+
+// Observe sends event to connected clients, it's Observer of hub
+func (h *EventHub) Observe(e Event) {
+	ev := eventJSON{Kind: e.Kind.String(), X: e.Cell.x, Y: e.Cell.y}
+	if e.From != nil {
+		ev.From = []int{e.From.x, e.From.y}
+	}
+	for _, c := range e.Path {
+		ev.Path = append(ev.Path, [2]int{c.x, c.y})
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	msg := []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", ev.Kind, data))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		select {
+		case client <- msg:
+		default:
+		}
+	}
+}
+
+// ServeHTTP streams events to client until it disconnects
+func (h *EventHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	// subscribe before headers so client gets every event after them
+	client := make(chan []byte, h.buffer)
+	h.mu.Lock()
+	h.clients[client] = true
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, client)
+		h.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg := <-client:
+			if _, err := w.Write(msg); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// recorder returns observer appending events to list
+func recorder(events *[]Event) Observer {
+	return func(e Event) {
+		*events = append(*events, e)
+	}
+}
+
+func count(events []Event, kind EventKind) int {
+	n := 0
+	for _, e := range events {
+		if e.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func TestObservedGenerator(t *testing.T) {
+	w, h := 6, 5
+	events := []Event{}
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, Observed(recorder(&events), DFS(NewStack(), 1)))
+	plain, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 1))
+	if maze.String() != plain.String() {
+		t.Error("observer changed maze")
+	}
+	// perfect maze has wall less than cells carved
+	if n := count(events, Carve); n != w*h-1 {
+		t.Errorf("expected %d carves, got %d", w*h-1, n)
+	}
+	if count(events, Backtrack) == 0 {
+		t.Error("expected backtracking")
+	}
+	for _, e := range events {
+		if e.Kind == Carve && !isConnected(e.From, e.Cell) {
+			t.Fatalf("wall of carve event %v %v is not removed", e.From.point, e.Cell.point)
+		}
+	}
+
+	// carves of tiles are emitted after tiles
+	events = events[:0]
+	NewMaze(16, 16, point{0, 0}, point{15, 15}, Observed(recorder(&events), Tiled(4, 1)))
+	if n := count(events, Carve); n != 16*16-1 {
+		t.Errorf("expected %d carves of tiles, got %d", 16*16-1, n)
+	}
+}
+
+func TestSolverEvents(t *testing.T) {
+	w, h := 8, 8
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 2))
+	maze.ResetVisitedCells()
+	for name, solver := range solvers {
+		events := []Event{}
+		maze.Observe(recorder(&events))
+		path, visited := []*cell{}, []*cell{}
+		solver(maze, maze.Begin(), maze.End(), &path, &visited)
+		maze.ResetVisitedCells()
+
+		last := events[len(events)-1]
+		if last.Kind != Found || len(last.Path) != len(path) {
+			t.Errorf("%s: expected found event with path last, got %v", name, last.Kind)
+		}
+		if count(events, Found) != 1 || count(events, Visit) == 0 {
+			t.Errorf("%s: unexpected events %d found, %d visits", name, count(events, Found), count(events, Visit))
+		}
+		if name == "dfs" && count(events, Backtrack) != len(visited)-len(path) {
+			t.Errorf("dfs: expected %d backtracks, got %d", len(visited)-len(path), count(events, Backtrack))
+		}
+		if name == "bfs" && count(events, Enqueue) != len(visited)-1 {
+			t.Errorf("bfs: expected %d enqueued, got %d", len(visited)-1, count(events, Enqueue))
+		}
+	}
+
+	maze.Observe(nil)
+	path, visited := []*cell{}, []*cell{}
+	FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited)
+	maze.ResetVisitedCells()
+}
+
+func TestGIFSink(t *testing.T) {
+	sink := NewGIFSink(white, yellow, green, red, black, 10, 10, 2, 4, 0)
+	maze, _ := NewMaze(6, 6, point{0, 0}, point{5, 5}, Observed(sink.Observe, DFS(NewStack(), 3)))
+	maze.ResetVisitedCells()
+	path, visited := []*cell{}, []*cell{}
+	FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited)
+
+	anim := sink.GIF()
+	if len(anim.Image) < 10 {
+		t.Errorf("expected frames of generation and search, got %d", len(anim.Image))
+	}
+	if anim.Config.Width != 60 || anim.Config.Height != 60 {
+		t.Errorf("unexpected size %dx%d", anim.Config.Width, anim.Config.Height)
+	}
+}
+
+func TestTerminalSink(t *testing.T) {
+	out := &bytes.Buffer{}
+	sink := NewTerminalSink(out, 1000)
+	maze, _ := NewMaze(4, 1, point{0, 0}, point{3, 0}, nil)
+	for x := 1; x < 4; x++ {
+		maze.RmWall(maze.cells[x-1][0], maze.cells[x][0])
+	}
+	maze.Observe(sink.Observe)
+	path, visited := []*cell{}, []*cell{}
+	FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited)
+
+	// only found event is shown
+	expected := "\x1b[H\x1b[2J+---+---+---+---+\n| S   *   *   E |\n+---+---+---+---+\nevents 7\n"
+	if out.String() != expected || sink.Err() != nil {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestEventHub(t *testing.T) {
+	hub := NewEventHub(100)
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected content type %s", ct)
+	}
+
+	maze, _ := NewMaze(2, 1, point{0, 0}, point{1, 0}, nil)
+	maze.Observe(hub.Observe)
+	maze.RmWall(maze.cells[0][0], maze.cells[1][0])
+	path, visited := []*cell{}, []*cell{}
+	FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited)
+
+	r := bufio.NewReader(resp.Body)
+	expected := []string{
+		"event: carve", `data: {"kind":"carve","x":1,"y":0,"from":[0,0]}`, "",
+		"event: visit", `data: {"kind":"visit","x":0,"y":0}`, "",
+		"event: enqueue", `data: {"kind":"enqueue","x":1,"y":0,"from":[0,0]}`, "",
+		"event: found", `data: {"kind":"found","x":1,"y":0,"path":[[1,0],[0,0]]}`, "",
+	}
+	for _, e := range expected {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSuffix(line, "\n") != e {
+			t.Errorf("expected %q, got %q", e, line)
+		}
+	}
+}
+
+func TestSinksOnTiled(t *testing.T) {
+	// sinks read whole maze while tiles are carved, run with -race
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	gifSink := NewGIFSink(white, yellow, green, red, black, 4, 4, 1, 16, 0)
+	termSink := NewTerminalSink(&bytes.Buffer{}, 16)
+	hub := NewEventHub(10)
+	events := []Event{}
+	obs := Broadcast(gifSink.Observe, termSink.Observe, hub.Observe, recorder(&events))
+	maze, _ := NewMaze(32, 32, point{0, 0}, point{31, 31}, Observed(obs, Tiled(4, 1)))
+
+	if n := count(events, Carve); n != 32*32-1 {
+		t.Errorf("expected %d carves, got %d", 32*32-1, n)
+	}
+	if len(gifSink.GIF().Image) < 32*32/16 || termSink.Err() != nil {
+		t.Errorf("expected frame every 16 carves, got %d", len(gifSink.GIF().Image))
+	}
+	expected, _ := NewMaze(32, 32, point{0, 0}, point{31, 31}, Tiled(4, 1))
+	if maze.String() != expected.String() {
+		t.Error("observed maze differs")
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Graph is maze as nodes of cells and weighted edges between them
+type Graph struct {
+	Nodes []point
+	Edges []Edge
+	begin int
+	end   int
+}
+
+// Edge joins nodes From and To, Weight is number of steps between them.
+// OneWay edge can be passed only from From to To
+type Edge struct {
+	From, To int
+	Weight   int
+	OneWay   bool
+}
+
+// CellGraph returns graph with node per cell of maze and edge per door,
+// masked cells are left out
+func CellGraph(m *Maze) *Graph {
+	g := &Graph{}
+	index := map[*cell]int{}
+	m.eachCell(func(c *cell) {
+		index[c] = g.add(m, c)
+	})
+	m.eachCell(func(c *cell) {
+		for _, n := range m.AdjacentCells(c, func(n *cell) bool { return isConnected(c, n) }) {
+			oneWay := !isConnected(n, c)
+			if oneWay || index[c] < index[n] {
+				g.Edges = append(g.Edges, Edge{index[c], index[n], 1, oneWay})
+			}
+		}
+	})
+	return g
+}
+
+// CompressedGraph returns graph with nodes of junctions, dead ends, begin
+// and end of maze. Corridors between them are edges weighted by length,
+// corridor is one way if any door on it is
+func CompressedGraph(m *Maze) *Graph {
+	g := &Graph{}
+	index := map[*cell]int{}
+	covered := map[*cell]bool{}
+	used := map[[2]*cell]bool{}
+
+	// cells joined to c by door in any direction
+	sides := func(c *cell) []*cell {
+		return m.AdjacentCells(c, func(n *cell) bool {
+			return isConnected(c, n) || isConnected(n, c)
+		})
+	}
+	isNode := func(c *cell) bool {
+		_, ok := index[c]
+		return ok
+	}
+	// walk corridors from node u to next nodes
+	walk := func(u *cell) {
+		for _, n := range sides(u) {
+			if used[[2]*cell{u, n}] {
+				continue
+			}
+			prev, cur, steps := u, n, 1
+			fwd, bwd := isConnected(u, n), isConnected(n, u)
+			for !isNode(cur) {
+				covered[cur] = true
+				next := sides(cur)[0]
+				if next == prev {
+					next = sides(cur)[1]
+				}
+				fwd = fwd && isConnected(cur, next)
+				bwd = bwd && isConnected(next, cur)
+				prev, cur, steps = cur, next, steps+1
+			}
+			used[[2]*cell{u, n}], used[[2]*cell{cur, prev}] = true, true
+			switch {
+			case fwd:
+				g.Edges = append(g.Edges, Edge{index[u], index[cur], steps, !bwd})
+			case bwd:
+				g.Edges = append(g.Edges, Edge{index[cur], index[u], steps, true})
+			}
+		}
+	}
+
+	m.eachCell(func(c *cell) {
+		if len(sides(c)) != 2 || c == m.Begin() || c == m.End() {
+			index[c] = g.add(m, c)
+			covered[c] = true
+		}
+	})
+	for _, n := range g.Nodes {
+		walk(m.cells[n.x][n.y])
+	}
+	// loops without junctions get node in first cell
+	m.eachCell(func(c *cell) {
+		if !covered[c] {
+			index[c] = g.add(m, c)
+			covered[c] = true
+			walk(c)
+		}
+	})
+	return g
+}
+
+// eachCell calls fn for cells of maze which are not masked, row by row
+func (m *Maze) eachCell(fn func(c *cell)) {
+	for y := 0; y < m.h; y++ {
+		for x := 0; x < m.w; x++ {
+			if !m.cells[x][y].masked {
+				fn(m.cells[x][y])
+			}
+		}
+	}
+}
+
+// add adds node of cell c and returns its index
+func (g *Graph) add(m *Maze, c *cell) int {
+	i := len(g.Nodes)
+	if c == m.Begin() {
+		g.begin = i
+	}
+	if c == m.End() {
+		g.end = i
+	}
+	g.Nodes = append(g.Nodes, c.point)
+	return i
+}
+
+// kind returns role of node i, begin, end or empty
+func (g *Graph) kind(i int) string {
+	switch i {
+	case g.begin:
+		return "begin"
+	case g.end:
+		return "end"
+	}
+	return ""
+}
+
+func nodeID(p point) string {
+	return fmt.Sprintf("c%d_%d", p.x, p.y)
+}
+
+// WriteDOT writes graph in Graphviz DOT format, nodes are pinned to cell
+// positions and two way edges have no arrows
+func (g *Graph) WriteDOT(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "digraph maze {")
+	for i, p := range g.Nodes {
+		fmt.Fprintf(b, "  %s [label=\"%d,%d\" pos=\"%d,%d!\"", nodeID(p), p.x, p.y, p.x, -p.y)
+		if k := g.kind(i); k != "" {
+			fmt.Fprintf(b, " kind=%s shape=doublecircle", k)
+		}
+		fmt.Fprintln(b, "]")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(b, "  %s -> %s [weight=%d label=%d", nodeID(g.Nodes[e.From]), nodeID(g.Nodes[e.To]), e.Weight, e.Weight)
+		if !e.OneWay {
+			fmt.Fprint(b, " dir=none")
+		}
+		fmt.Fprintln(b, "]")
+	}
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
+
+// WriteGraphML writes graph in GraphML format, nodes have x, y and kind
+// data, edges have weight and are directed only if they are one way
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(b, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(b, `  <key id="x" for="node" attr.name="x" attr.type="int"/>`)
+	fmt.Fprintln(b, `  <key id="y" for="node" attr.name="y" attr.type="int"/>`)
+	fmt.Fprintln(b, `  <key id="kind" for="node" attr.name="kind" attr.type="string"/>`)
+	fmt.Fprintln(b, `  <key id="weight" for="edge" attr.name="weight" attr.type="int"/>`)
+	fmt.Fprintln(b, `  <graph id="maze" edgedefault="undirected">`)
+	for i, p := range g.Nodes {
+		fmt.Fprintf(b, `    <node id="%s"><data key="x">%d</data><data key="y">%d</data>`, nodeID(p), p.x, p.y)
+		if k := g.kind(i); k != "" {
+			fmt.Fprintf(b, `<data key="kind">%s</data>`, k)
+		}
+		fmt.Fprintln(b, `</node>`)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(b, `    <edge source="%s" target="%s" directed="%t"><data key="weight">%d</data></edge>`+"\n",
+			nodeID(g.Nodes[e.From]), nodeID(g.Nodes[e.To]), e.OneWay, e.Weight)
+	}
+	fmt.Fprintln(b, `  </graph>`)
+	fmt.Fprintln(b, `</graphml>`)
+	return b.Flush()
+}
+
+// WriteAdjacency writes line per node, node followed by nodes reachable
+// from it with weights, like "c0_0: c1_0:1 c0_1:3"
+func (g *Graph) WriteAdjacency(w io.Writer) error {
+	out := make([][]Edge, len(g.Nodes))
+	for _, e := range g.Edges {
+		out[e.From] = append(out[e.From], e)
+		if !e.OneWay {
+			out[e.To] = append(out[e.To], Edge{e.To, e.From, e.Weight, false})
+		}
+	}
+	b := bufio.NewWriter(w)
+	for i, p := range g.Nodes {
+		fmt.Fprintf(b, "%s:", nodeID(p))
+		for _, e := range out[i] {
+			fmt.Fprintf(b, " %s:%d", nodeID(g.Nodes[e.To]), e.Weight)
+		}
+		fmt.Fprintln(b)
+	}
+	return b.Flush()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCellGraph(t *testing.T) {
+	maze, _ := NewMaze(2, 2, point{0, 0}, point{1, 1}, nil)
+	c := maze.cells
+	maze.RmWall(c[0][0], c[1][0])
+	maze.RmWall(c[0][0], c[0][1])
+	maze.AddOneWayDoor(c[1][0], c[1][1])
+	g := CellGraph(maze)
+
+	if len(g.Nodes) != 4 || len(g.Edges) != 3 {
+		t.Fatalf("expected 4 nodes and 3 edges, got %d and %d", len(g.Nodes), len(g.Edges))
+	}
+
+	out := &bytes.Buffer{}
+	if err := g.WriteDOT(out); err != nil {
+		t.Fatal(err)
+	}
+	expected := `digraph maze {
+  c0_0 [label="0,0" pos="0,0!" kind=begin shape=doublecircle]
+  c1_0 [label="1,0" pos="1,0!"]
+  c0_1 [label="0,1" pos="0,-1!"]
+  c1_1 [label="1,1" pos="1,-1!" kind=end shape=doublecircle]
+  c0_0 -> c1_0 [weight=1 label=1 dir=none]
+  c0_0 -> c0_1 [weight=1 label=1 dir=none]
+  c1_0 -> c1_1 [weight=1 label=1]
+}
+`
+	if out.String() != expected {
+		t.Errorf("unexpected dot\n%s", out)
+	}
+
+	out.Reset()
+	if err := g.WriteAdjacency(out); err != nil {
+		t.Fatal(err)
+	}
+	expected = "c0_0: c1_0:1 c0_1:1\nc1_0: c0_0:1 c1_1:1\nc0_1: c0_0:1\nc1_1:\n"
+	if out.String() != expected {
+		t.Errorf("unexpected adjacency\n%s", out)
+	}
+
+	out.Reset()
+	if err := g.WriteGraphML(out); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<node id="c1_1"><data key="x">1</data><data key="y">1</data><data key="kind">end</data></node>`,
+		`<edge source="c1_0" target="c1_1" directed="true"><data key="weight">1</data></edge>`,
+	} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected %s in\n%s", s, out)
+		}
+	}
+}
+
+func TestCompressedGraph(t *testing.T) {
+	w, h := 15, 15
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 1))
+	g := CompressedGraph(maze)
+
+	// tree keeps cells count as total weight plus one
+	total := 0
+	for _, e := range g.Edges {
+		total += e.Weight
+		if e.OneWay {
+			t.Error("unexpected one way edge")
+		}
+	}
+	if total != w*h-1 || len(g.Edges) != len(g.Nodes)-1 {
+		t.Errorf("expected tree of %d steps, got %d edges of %d steps", w*h-1, len(g.Edges), total)
+	}
+	for i, p := range g.Nodes {
+		c := maze.cells[p.x][p.y]
+		doors := len(maze.AdjacentCells(c, func(n *cell) bool { return isConnected(c, n) }))
+		if doors == 2 && i != g.begin && i != g.end {
+			t.Errorf("corridor cell %v is node", p)
+		}
+	}
+
+	// loop without junctions
+	loop, _ := NewMaze(2, 2, point{0, 0}, point{0, 0}, nil)
+	c := loop.cells
+	loop.RmWall(c[0][0], c[1][0])
+	loop.RmWall(c[1][0], c[1][1])
+	loop.RmWall(c[1][1], c[0][1])
+	loop.RmWall(c[0][1], c[0][0])
+	g = CompressedGraph(loop)
+	if len(g.Nodes) != 1 || len(g.Edges) != 1 || g.Edges[0].Weight != 4 {
+		t.Errorf("expected loop of 4 steps, got %v", g.Edges)
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"math"
+)
+
+// Topology defines shape of cells and which cells are adjacent. Cells of
+// every topology are stored in Maze.cells[x][y], so generators and solvers
+// work on any of them through AdjacentCells, RmWall and isConnected
+type Topology interface {
+	// Neighbors returns points of cells adjacent to p
+	Neighbors(m *Maze, p point) []point
+	// Walls returns walls of cell at p for cell size in pixels
+	Walls(m *Maze, p point, size float64) []wall
+	// ImageSize returns size of maze image in pixels for cell size
+	ImageSize(m *Maze, size float64) (w, h float64)
+}
+
+type vec struct {
+	x, y float64
+}
+
+// wall is a polyline between cell and its neighbor, outer walls have no
+// neighbor behind. Wrapped walls are on border of maze which wraps around,
+// they are drawn on both sides
+type wall struct {
+	line     []vec
+	neighbor point
+	outer    bool
+	wrapped  bool
+}
+
+// Hexagonal returns generator which turns maze into grid of flat topped
+// hexagons, odd columns are shifted half cell down
+func Hexagonal(generator func(*Maze) (*Maze, []*cell)) func(*Maze) (*Maze, []*cell) {
+	return withTopology(hexGrid{}, generator)
+}
+
+// Triangular returns generator which turns maze into grid of triangles,
+// cell is pointing up if x+y is even
+func Triangular(generator func(*Maze) (*Maze, []*cell)) func(*Maze) (*Maze, []*cell) {
+	return withTopology(triangleGrid{}, generator)
+}
+
+// Polar returns generator which turns maze into theta maze of h concentric
+// rings, cells[x][y] is x-th cell of ring y. Inner ring is a single cell and
+// rings are subdivided so cells keep roughly square, maze should be at
+// least PolarWidth(h) wide, cells beyond ring size are masked
+func Polar(generator func(*Maze) (*Maze, []*cell)) func(*Maze) (*Maze, []*cell) {
+	return func(m *Maze) (*Maze, []*cell) {
+		g := newPolarGrid(m.h)
+		if m.w < g.counts[m.h-1] {
+			panic("w should be at least PolarWidth(h)")
+		}
+		for x := range m.cells {
+			for y := range m.cells[x] {
+				if x >= g.counts[y] {
+					m.cells[x][y].masked = true
+				}
+			}
+		}
+		if m.Begin().masked || m.End().masked {
+			panic("start and end point should be inside maze")
+		}
+		return withTopology(g, generator)(m)
+	}
+}
+
+// PolarWidth returns number of cells in outer ring of polar maze
+func PolarWidth(rings int) int {
+	g := newPolarGrid(rings)
+	return g.counts[rings-1]
+}
+
+func withTopology(t Topology, generator func(*Maze) (*Maze, []*cell)) func(*Maze) (*Maze, []*cell) {
+	return func(m *Maze) (*Maze, []*cell) {
+		m.topology = t
+		if generator == nil {
+			return m, nil
+		}
+		return generator(m)
+	}
+}
+
+func (m *Maze) inside(p point) bool {
+	return p.x >= 0 && p.x < m.w && p.y >= 0 && p.y < m.h
+}
+
+// planar is implemented by square grids, doors between cells next to each
+// other are kept in cell left, up, right, down fields
+type planar interface {
+	planar()
+}
+
+// squareGrid is default grid, its edges may wrap around
+type squareGrid struct {
+	wrap Wrap
+}
+
+func (squareGrid) planar() {}
+
+// wraps returns if maze wraps around horizontally and vertically, mazes
+// narrower than 3 cells don't wrap
+func (g squareGrid) wraps(m *Maze) (h, v bool) {
+	return g.wrap != WrapNone && m.w > 2, g.wrap == WrapTorus && m.h > 2
+}
+
+func (g squareGrid) Neighbors(m *Maze, p point) []point {
+	points := make([]point, 0, 4)
+	h, v := g.wraps(m)
+	for _, n := range []point{{p.x - 1, p.y}, {p.x + 1, p.y}, {p.x, p.y - 1}, {p.x, p.y + 1}} {
+		if h {
+			n.x = (n.x + m.w) % m.w
+		}
+		if v {
+			n.y = (n.y + m.h) % m.h
+		}
+		if m.inside(n) {
+			points = append(points, n)
+		}
+	}
+	return points
+}
+
+// unwrap returns distance between neighbors across wrapped edges as if
+// they were next to each other
+func (g squareGrid) unwrap(m *Maze, dx, dy int) (int, int, bool) {
+	h, v := g.wraps(m)
+	switch {
+	case h && dx == m.w-1:
+		return -1, dy, true
+	case h && dx == 1-m.w:
+		return 1, dy, true
+	case v && dy == m.h-1:
+		return dx, -1, true
+	case v && dy == 1-m.h:
+		return dx, 1, true
+	}
+	return dx, dy, false
+}
+
+func (g squareGrid) Walls(m *Maze, p point, size float64) []wall {
+	x0, y0 := float64(p.x)*size, float64(p.y)*size
+	x1, y1 := x0+size, y0+size
+	h, v := g.wraps(m)
+	return []wall{
+		{[]vec{{x0, y0}, {x1, y0}}, point{p.x, (p.y - 1 + m.h) % m.h}, p.y == 0 && !v, p.y == 0 && v},
+		{[]vec{{x1, y0}, {x1, y1}}, point{(p.x + 1) % m.w, p.y}, p.x == m.w-1 && !h, p.x == m.w-1 && h},
+		{[]vec{{x1, y1}, {x0, y1}}, point{p.x, (p.y + 1) % m.h}, p.y == m.h-1 && !v, p.y == m.h-1 && v},
+		{[]vec{{x0, y1}, {x0, y0}}, point{(p.x - 1 + m.w) % m.w, p.y}, p.x == 0 && !h, p.x == 0 && h},
+	}
+}
+
+func (squareGrid) ImageSize(m *Maze, size float64) (float64, float64) {
+	return float64(m.w) * size, float64(m.h) * size
+}
+
+// hexGrid is grid of flat topped hexagons, cell size is hexagon width
+type hexGrid struct{}
+
+// hexNeighbor returns neighbor across i-th edge, edges go clockwise from
+// south east one
+func hexNeighbor(p point, i int) point {
+	odd := p.x & 1
+	switch i {
+	case 0: // south east
+		return point{p.x + 1, p.y + odd}
+	case 1: // south
+		return point{p.x, p.y + 1}
+	case 2: // south west
+		return point{p.x - 1, p.y + odd}
+	case 3: // north west
+		return point{p.x - 1, p.y - 1 + odd}
+	case 4: // north
+		return point{p.x, p.y - 1}
+	default: // north east
+		return point{p.x + 1, p.y - 1 + odd}
+	}
+}
+
+func (hexGrid) Neighbors(m *Maze, p point) []point {
+	points := make([]point, 0, 6)
+	for i := 0; i < 6; i++ {
+		if n := hexNeighbor(p, i); m.inside(n) {
+			points = append(points, n)
+		}
+	}
+	return points
+}
+
+func (hexGrid) Walls(m *Maze, p point, size float64) []wall {
+	r := size / 2
+	hh := math.Sqrt(3) * r
+	cx := r + float64(p.x)*1.5*r
+	cy := hh/2 + float64(p.y)*hh + float64(p.x&1)*hh/2
+	vertex := func(i int) vec {
+		a := float64(i) * math.Pi / 3
+		return vec{cx + r*math.Cos(a), cy + r*math.Sin(a)}
+	}
+	walls := make([]wall, 6)
+	for i := range walls {
+		n := hexNeighbor(p, i)
+		walls[i] = wall{[]vec{vertex(i), vertex(i + 1)}, n, !m.inside(n), false}
+	}
+	return walls
+}
+
+func (hexGrid) ImageSize(m *Maze, size float64) (float64, float64) {
+	r := size / 2
+	return r * (1.5*float64(m.w) + 0.5), math.Sqrt(3) * r * (float64(m.h) + 0.5)
+}
+
+// triangleGrid is grid of triangles, cell size is triangle side
+type triangleGrid struct{}
+
+func upright(p point) bool {
+	return (p.x+p.y)%2 == 0
+}
+
+func (triangleGrid) Neighbors(m *Maze, p point) []point {
+	points := make([]point, 0, 3)
+	third := point{p.x, p.y - 1}
+	if upright(p) {
+		third = point{p.x, p.y + 1}
+	}
+	for _, n := range []point{{p.x - 1, p.y}, {p.x + 1, p.y}, third} {
+		if m.inside(n) {
+			points = append(points, n)
+		}
+	}
+	return points
+}
+
+func (triangleGrid) Walls(m *Maze, p point, size float64) []wall {
+	hgt := size * math.Sqrt(3) / 2
+	x0 := float64(p.x) * size / 2
+	y0, y1 := float64(p.y)*hgt, float64(p.y+1)*hgt
+	left, right := point{p.x - 1, p.y}, point{p.x + 1, p.y}
+	if upright(p) {
+		bl, apex, br := vec{x0, y1}, vec{x0 + size/2, y0}, vec{x0 + size, y1}
+		down := point{p.x, p.y + 1}
+		return []wall{
+			{[]vec{bl, apex}, left, !m.inside(left), false},
+			{[]vec{apex, br}, right, !m.inside(right), false},
+			{[]vec{br, bl}, down, !m.inside(down), false},
+		}
+	}
+	tl, tr, apex := vec{x0, y0}, vec{x0 + size, y0}, vec{x0 + size/2, y1}
+	up := point{p.x, p.y - 1}
+	return []wall{
+		{[]vec{tl, tr}, up, !m.inside(up), false},
+		{[]vec{tr, apex}, right, !m.inside(right), false},
+		{[]vec{apex, tl}, left, !m.inside(left), false},
+	}
+}
+
+func (triangleGrid) ImageSize(m *Maze, size float64) (float64, float64) {
+	return float64(m.w+1) * size / 2, float64(m.h) * size * math.Sqrt(3) / 2
+}
+
+// polarGrid is grid of concentric rings, cell size is ring height
+type polarGrid struct {
+	counts []int // cells in ring
+}
+
+func newPolarGrid(rings int) polarGrid {
+	counts := make([]int, rings)
+	counts[0] = 1
+	for r := 1; r < rings; r++ {
+		// split cells when they get twice as wide as ring height
+		cellWidth := 2 * math.Pi * float64(r) / float64(counts[r-1])
+		counts[r] = counts[r-1] * int(math.Round(cellWidth))
+	}
+	return polarGrid{counts}
+}
+
+func (g polarGrid) Neighbors(m *Maze, p point) []point {
+	points := make([]point, 0, 4)
+	n := g.counts[p.y]
+	if n > 1 {
+		points = append(points, point{(p.x + 1) % n, p.y})
+		if n > 2 {
+			points = append(points, point{(p.x - 1 + n) % n, p.y})
+		}
+	}
+	if p.y > 0 {
+		points = append(points, point{p.x / (n / g.counts[p.y-1]), p.y - 1})
+	}
+	if p.y < len(g.counts)-1 {
+		ratio := g.counts[p.y+1] / n
+		for i := 0; i < ratio; i++ {
+			points = append(points, point{p.x*ratio + i, p.y + 1})
+		}
+	}
+	return points
+}
+
+func (g polarGrid) Walls(m *Maze, p point, size float64) []wall {
+	c := float64(len(g.counts)) * size
+	n := g.counts[p.y]
+	t0 := 2 * math.Pi * float64(p.x) / float64(n)
+	t1 := 2 * math.Pi * float64(p.x+1) / float64(n)
+	r0, r1 := float64(p.y)*size, float64(p.y+1)*size
+	at := func(r, t float64) vec {
+		return vec{c + r*math.Cos(t), c + r*math.Sin(t)}
+	}
+	arc := func(r, t0, t1 float64) []vec {
+		steps := int(math.Ceil((t1-t0)*r/4)) + 1
+		line := make([]vec, steps+1)
+		for i := range line {
+			line[i] = at(r, t0+(t1-t0)*float64(i)/float64(steps))
+		}
+		return line
+	}
+
+	walls := []wall{}
+	if p.y > 0 {
+		parent := point{p.x / (n / g.counts[p.y-1]), p.y - 1}
+		walls = append(walls, wall{arc(r0, t0, t1), parent, false, false})
+		if n > 1 {
+			walls = append(walls,
+				wall{[]vec{at(r0, t0), at(r1, t0)}, point{(p.x - 1 + n) % n, p.y}, false, false},
+				wall{[]vec{at(r0, t1), at(r1, t1)}, point{(p.x + 1) % n, p.y}, false, false},
+			)
+		}
+	}
+	if p.y == len(g.counts)-1 {
+		return append(walls, wall{arc(r1, t0, t1), point{}, true, false})
+	}
+	ratio := g.counts[p.y+1] / n
+	for i := 0; i < ratio; i++ {
+		c0 := t0 + (t1-t0)*float64(i)/float64(ratio)
+		c1 := t0 + (t1-t0)*float64(i+1)/float64(ratio)
+		walls = append(walls, wall{arc(r1, c0, c1), point{p.x*ratio + i, p.y + 1}, false, false})
+	}
+	return walls
+}
+
+func (g polarGrid) ImageSize(m *Maze, size float64) (float64, float64) {
+	d := 2 * float64(len(g.counts)) * size
+	return d, d
+}
+
+// closedWalls calls fn for every wall which should be drawn, walls shared
+// by two cells are reported once
+func closedWalls(m *Maze, size float64, fn func(line []vec)) {
+	for x := range m.cells {
+		for _, c := range m.cells[x] {
+			if c.masked {
+				continue
+			}
+			for _, w := range m.topology.Walls(m, c.point, size) {
+				if w.outer {
+					fn(w.line)
+					continue
+				}
+				n := m.cells[w.neighbor.x][w.neighbor.y]
+				if n.masked {
+					fn(w.line)
+					continue
+				}
+				if isConnected(c, n) {
+					continue
+				}
+				if w.wrapped || c.y < n.y || c.y == n.y && c.x < n.x {
+					fn(w.line)
+				}
+			}
+		}
+	}
+}
+
+// drawGrid draws maze of any topology with walls as lines, image is
+// padded by wall width so outer walls are not clipped
+func drawGrid(m *Maze, fill, border color.Color, size, ww int) *image.Paletted {
+	w, h := m.topology.ImageSize(m, float64(size))
+	pad := float64(ww)
+	r := image.Rect(0, 0, int(math.Ceil(w+2*pad)), int(math.Ceil(h+2*pad)))
+	img := image.NewPaletted(r, palette.WebSafe)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.Set(x, y, fill)
+		}
+	}
+	closedWalls(m, float64(size), func(line []vec) {
+		for i := 1; i < len(line); i++ {
+			a := vec{line[i-1].x + pad, line[i-1].y + pad}
+			b := vec{line[i].x + pad, line[i].y + pad}
+			drawLine(img, a, b, float64(ww)/2, border)
+		}
+	})
+	return img
+}
+
+// drawGridPath draws line through centers of path cells over maze image
+// of drawGrid, line is twice as wide as walls
+func drawGridPath(m *Maze, img *image.Paletted, path []*cell, c color.Color, size, ww int) {
+	pad := float64(ww)
+	for i := 1; i < len(path); i++ {
+		a, b := m.cellCenter(path[i-1].point, float64(size)), m.cellCenter(path[i].point, float64(size))
+		drawLine(img, vec{a.x + pad, a.y + pad}, vec{b.x + pad, b.y + pad}, float64(ww), c)
+	}
+}
+
+// cellCenter returns mean of wall ends of cell at p, it's inside of cell
+// for convex cells of grids
+func (m *Maze) cellCenter(p point, size float64) vec {
+	sum, n := vec{}, 0.0
+	for _, w := range m.topology.Walls(m, p, size) {
+		for _, v := range w.line {
+			sum.x, sum.y, n = sum.x+v.x, sum.y+v.y, n+1
+		}
+	}
+	return vec{sum.x / n, sum.y / n}
+}
+
+// drawLine sets pixels which centers are closer than r to segment a, b
+func drawLine(img *image.Paletted, a, b vec, r float64, c color.Color) {
+	bounds := image.Rect(
+		int(math.Floor(math.Min(a.x, b.x)-r)), int(math.Floor(math.Min(a.y, b.y)-r)),
+		int(math.Ceil(math.Max(a.x, b.x)+r)), int(math.Ceil(math.Max(a.y, b.y)+r)),
+	).Intersect(img.Bounds())
+	dx, dy := b.x-a.x, b.y-a.y
+	l2 := dx*dx + dy*dy
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			t := 0.0
+			if l2 > 0 {
+				t = math.Max(0, math.Min(1, ((px-a.x)*dx+(py-a.y)*dy)/l2))
+			}
+			ex, ey := px-(a.x+t*dx), py-(a.y+t*dy)
+			if ex*ex+ey*ey <= r*r {
+				img.Set(x, y, c)
+			}
+		}
+	}
+}
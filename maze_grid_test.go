@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGridTopologies(t *testing.T) {
+	data := []struct {
+		name       string
+		w, h       int
+		exit       point
+		topology   func(func(*Maze) (*Maze, []*cell)) func(*Maze) (*Maze, []*cell)
+		neighbours int
+	}{
+		{"hex", 12, 10, point{11, 9}, Hexagonal, 6},
+		{"triangle", 20, 10, point{19, 9}, Triangular, 3},
+		{"polar", PolarWidth(8), 8, point{3, 7}, Polar, 0},
+	}
+
+	for _, d := range data {
+		maze, _ := NewMaze(d.w, d.h, point{0, 0}, d.exit, d.topology(DFS(NewStack(), 1)))
+
+		cells, doors := 0, 0
+		for x := range maze.cells {
+			for _, c := range maze.cells[x] {
+				if c.masked {
+					continue
+				}
+				cells++
+				if !c.visited {
+					t.Errorf("%s: cell %v not visited", d.name, c.point)
+				}
+				adjacent := maze.AdjacentCells(c, func(*cell) bool { return true })
+				if d.neighbours > 0 && len(adjacent) > d.neighbours {
+					t.Errorf("%s: cell %v has %d neighbours", d.name, c.point, len(adjacent))
+				}
+				for _, n := range adjacent {
+					if isConnected(c, n) != isConnected(n, c) {
+						t.Errorf("%s: door %v %v is one way", d.name, c.point, n.point)
+					}
+					if isConnected(c, n) {
+						doors++
+					}
+				}
+			}
+		}
+		// perfect maze is a spanning tree
+		if doors/2 != cells-1 {
+			t.Errorf("%s: expected %d doors for %d cells, got %d", d.name, cells-1, cells, doors/2)
+		}
+		maze.ResetVisitedCells()
+
+		path := make([]*cell, 0, d.w)
+		visited := make([]*cell, 0, d.w)
+		if !FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited) {
+			t.Errorf("%s: path not found", d.name)
+		}
+		maze.ResetVisitedCells()
+
+		img := Draw(maze, white, black, 20, 20, 2)
+		if img.Bounds().Empty() {
+			t.Errorf("%s: empty image", d.name)
+		}
+		var svg bytes.Buffer
+		if err := DrawSVG(&svg, maze, white, black, 20, 20, 2); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(svg.String(), "<path") {
+			t.Errorf("%s: no walls in svg", d.name)
+		}
+
+		if f, err := os.Create(filepath.Join(t.TempDir(), "maze-"+d.name+".png")); err == nil {
+			if err = png.Encode(f, img); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+		}
+	}
+}
+
+func TestPolarRings(t *testing.T) {
+	g := newPolarGrid(4)
+	for i, expected := range []int{1, 6, 12, 24} {
+		if g.counts[i] != expected {
+			t.Errorf("ring %d expected %d cells, got %d", i, expected, g.counts[i])
+		}
+	}
+	if w := PolarWidth(4); w != 24 {
+		t.Errorf("expected width 24, got %d", w)
+	}
+	maze, _ := NewMaze(24, 4, point{0, 0}, point{23, 3}, Polar(nil))
+	if !maze.cells[6][1].masked || maze.cells[5][1].masked {
+		t.Error("expected cells beyond ring size masked")
+	}
+	// centre opens to all cells of first ring
+	if n := len(maze.AdjacentCells(maze.Begin(), func(*cell) bool { return true })); n != 6 {
+		t.Errorf("expected 6 neighbours of centre, got %d", n)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"image"
+)
+
+// ImportOptions tunes ImportMazeImage, zero values are detected from image.
+// Pixels darker than Threshold are walls, default is 128
+type ImportOptions struct {
+	Cols, Rows int
+	Threshold  uint8
+}
+
+// ImportMazeImage reconstructs square maze from picture of it, dark walls
+// on light background like Draw makes, transparent pixels are background.
+// Wall thickness is found from dark runs crossing walls and cell size from
+// regular spacing of wall lines, each wall is checked in the middle of
+// its cell side so corners and specks of noise don't matter.
+// Gaps in outer wall become begin and end, otherwise they are top left
+// and bottom right cells
+func ImportMazeImage(img image.Image, opts ImportOptions) (*Maze, error) {
+	if opts.Threshold == 0 {
+		opts.Threshold = 128
+	}
+	b := img.Bounds()
+	dark := make([][]bool, b.Dx())
+	colDark, rowDark := make([]int, b.Dx()), make([]int, b.Dy())
+	for x := range dark {
+		dark[x] = make([]bool, b.Dy())
+		for y := range dark[x] {
+			r, g, bl, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			lum := (299*r + 587*g + 114*bl) / 1000 >> 8
+			if a != 0 && lum < uint32(opts.Threshold) {
+				dark[x][y] = true
+				colDark[x]++
+				rowDark[y]++
+			}
+		}
+	}
+	// outer walls are the first and last lines which are mostly dark,
+	// specks of noise around maze are left out
+	x0, x1 := darkLines(colDark)
+	y0, y1 := darkLines(rowDark)
+	box := image.Rect(x0, y0, x1, y1)
+	if box.Empty() {
+		return nil, errors.New("no walls found")
+	}
+
+	// rows of pixels are scanned for vertical walls and columns for
+	// horizontal ones
+	at := func(x, y int) bool { return dark[x][y] }
+	across := func(x, y int) bool { return dark[y][x] }
+	crossed := map[int]int{}
+	crossings(at, box, crossed)
+	crossings(across, transpose(box), crossed)
+	t := mode(crossed)
+	if t < 1 {
+		t = 1
+	}
+	cols, rows := opts.Cols, opts.Rows
+	if cols == 0 {
+		cols = cellCount(at, box, t)
+	}
+	if rows == 0 {
+		rows = cellCount(across, transpose(box), t)
+	}
+	if cols < 1 || rows < 1 {
+		return nil, errors.New("grid not found")
+	}
+
+	// boundary i of cells is line at min + i*size
+	bx := func(i int) int { return box.Min.X + i*box.Dx()/cols }
+	by := func(i int) int { return box.Min.Y + i*box.Dy()/rows }
+	r := t/2 + 1
+	// wall returns if there is wall on vertical line x between y0 and y1
+	wall := func(get func(x, y int) bool, x, y0, y1 int, max int) bool {
+		// middle half of cell side
+		y0, y1 = y0+(y1-y0)/4, y1-(y1-y0)/4
+		for c := x - r; c <= x+r; c++ {
+			if c < 0 || c >= max || y1 <= y0 {
+				continue
+			}
+			n := 0
+			for y := y0; y < y1; y++ {
+				if get(c, y) {
+					n++
+				}
+			}
+			if 10*n >= 6*(y1-y0) {
+				return true
+			}
+		}
+		return false
+	}
+	vwall := func(i, y int) bool { return wall(at, bx(i), by(y), by(y+1), b.Dx()) }
+	hwall := func(x, i int) bool { return wall(across, by(i), bx(x), bx(x+1), b.Dy()) }
+
+	// gaps in outer wall
+	gaps := []point{}
+	for x := 0; x < cols; x++ {
+		if !hwall(x, 0) {
+			gaps = append(gaps, point{x, 0})
+		}
+	}
+	for y := 0; y < rows; y++ {
+		if !vwall(0, y) {
+			gaps = append(gaps, point{0, y})
+		}
+		if !vwall(cols, y) {
+			gaps = append(gaps, point{cols - 1, y})
+		}
+	}
+	for x := 0; x < cols; x++ {
+		if !hwall(x, rows) {
+			gaps = append(gaps, point{x, rows - 1})
+		}
+	}
+	entry, exit := point{0, 0}, point{cols - 1, rows - 1}
+	if len(gaps) >= 2 {
+		entry, exit = gaps[0], gaps[len(gaps)-1]
+	}
+
+	m, _ := NewMaze(cols, rows, entry, exit, nil)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			if x < cols-1 && !vwall(x+1, y) {
+				m.RmWall(m.cells[x][y], m.cells[x+1][y])
+			}
+			if y < rows-1 && !hwall(x, y+1) {
+				m.RmWall(m.cells[x][y], m.cells[x][y+1])
+			}
+		}
+	}
+	return m, nil
+}
+
+// darkLines returns first and after last line with at least half as many
+// dark pixels as the darkest line
+func darkLines(count []int) (int, int) {
+	most := 0
+	for _, n := range count {
+		if n > most {
+			most = n
+		}
+	}
+	first, last := 0, -1
+	for i, n := range count {
+		if most > 0 && 2*n >= most {
+			if last < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	return first, last + 1
+}
+
+func transpose(r image.Rectangle) image.Rectangle {
+	return image.Rect(r.Min.Y, r.Min.X, r.Max.Y, r.Max.X)
+}
+
+// crossings counts lengths of dark runs inside r which cross longer wall,
+// so specks of noise and walls along rows are left out, runs touching
+// edges of r may be cut. Light specks don't split runs
+func crossings(get func(x, y int) bool, r image.Rectangle, count map[int]int) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for _, run := range darkRuns(get, y, r, 2) {
+			l := run[1] - run[0]
+			if run[0] > r.Min.X && run[1] < r.Max.X &&
+				darkRun(get, (run[0]+run[1]-1)/2, y, r) > l+1 {
+				count[l]++
+			}
+		}
+	}
+}
+
+// mode returns most common length in count, 0 if it is empty
+func mode(count map[int]int) int {
+	mode := 0
+	for l, n := range count {
+		if n > count[mode] || n == count[mode] && l < mode {
+			mode = l
+		}
+	}
+	return mode
+}
+
+// cellCount returns number of cells along rows of r for wall thickness t.
+// Wall lines of grid are where rows cross vertical walls and where
+// horizontal walls end, count is the fewest cells of equal size which put
+// every such line on cell boundary
+func cellCount(get func(x, y int) bool, r image.Rectangle, t int) int {
+	// wall between cells spreads half wall to both sides of boundary, so
+	// horizontal wall ends on boundary or half wall after it where
+	// vertical wall joins, then wall before end runs across rows
+	half := (t + 1) / 2
+	lines := make([]int, r.Dx()+1)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for _, run := range darkRuns(get, y, r, half) {
+			start, end := run[0], run[1]
+			if end-start > 2*t {
+				// ends at outer wall are on boundary anyway
+				if start > r.Min.X {
+					b := start
+					if darkRun(get, start+t-1, y, r) > t+1 {
+						b += half - 1
+					}
+					lines[b-r.Min.X]++
+				}
+				if end < r.Max.X {
+					b := end
+					if darkRun(get, end-t, y, r) > t+1 {
+						b -= half
+					}
+					lines[b-r.Min.X]++
+				}
+			} else if c := (start + end - 1) / 2; darkRun(get, c, y, r) > t+1 {
+				// specks of noise are not part of long wall
+				lines[c-r.Min.X]++
+			}
+		}
+	}
+
+	// line is where most rows see it within half wall, lines seen by fewer
+	// rows than wall is thick are noise
+	d := t/4 + 1
+	found := []int{}
+	for x := range lines {
+		n, most := 0, true
+		for i := x - half; i <= x+half; i++ {
+			if i >= 0 && i < len(lines) {
+				n += lines[i]
+				most = most && lines[i] <= lines[x]
+			}
+		}
+		if lines[x] > 0 && most && n >= t {
+			found = append(found, x)
+		}
+	}
+	for cols := 1; cols <= r.Dx()/(t+1); cols++ {
+		fits := true
+		for _, x := range found {
+			// nearest boundary of cols cells
+			i := (x*cols + r.Dx()/2) / r.Dx()
+			if abs(x-i*r.Dx()/cols) > d {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return cols
+		}
+	}
+	return 0
+}
+
+// darkRuns returns starts and ends of dark runs along row y of r, light
+// gaps shorter than gap are specks of noise inside wall
+func darkRuns(get func(x, y int) bool, y int, r image.Rectangle, gap int) [][2]int {
+	runs := [][2]int{}
+	for x := r.Min.X; x < r.Max.X; x++ {
+		if !get(x, y) {
+			continue
+		}
+		start := x
+		for x < r.Max.X && get(x, y) {
+			x++
+		}
+		if n := len(runs); n > 0 && start-runs[n-1][1] < gap {
+			runs[n-1][1] = x
+		} else {
+			runs = append(runs, [2]int{start, x})
+		}
+	}
+	return runs
+}
+
+// darkRun returns length of dark run across rows through x, y inside r
+func darkRun(get func(x, y int) bool, x, y int, r image.Rectangle) int {
+	y0, y1 := y, y
+	for y0 > r.Min.Y && get(x, y0-1) {
+		y0--
+	}
+	for y1 < r.Max.Y-1 && get(x, y1+1) {
+		y1++
+	}
+	return y1 - y0 + 1
+}
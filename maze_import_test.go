@@ -0,0 +1,90 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"testing"
+)
+
+func TestImportMazeImage(t *testing.T) {
+	// small mazes have few walls inside to find grid from
+	for _, grid := range []struct{ w, h int }{{12, 9}, {2, 2}, {2, 6}, {6, 2}, {3, 7}, {7, 3}} {
+		for seed := int64(1); seed <= 3; seed++ {
+			maze, _ := NewMaze(grid.w, grid.h, point{0, 0}, point{grid.w - 1, grid.h - 1}, DFS(NewStack(), seed))
+			maze.ResetVisitedCells()
+			for _, size := range []struct{ cw, ww int }{{10, 2}, {16, 2}, {20, 2}, {25, 4}, {30, 3}} {
+				img := Draw(maze, white, black, size.cw, size.cw, size.ww)
+				imported, err := ImportMazeImage(img, ImportOptions{})
+				if err != nil {
+					t.Fatalf("%dx%d cell %d, wall %d: %v", grid.w, grid.h, size.cw, size.ww, err)
+				}
+				if imported.String() != maze.String() {
+					t.Errorf("%dx%d cell %d, wall %d: unexpected maze\n%s", grid.w, grid.h, size.cw, size.ww, imported)
+				}
+			}
+		}
+	}
+
+	w, h := 12, 9
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 1))
+	maze.ResetVisitedCells()
+
+	// walls on transparent background
+	img := Draw(maze, white, black, 16, 16, 2)
+	walls := image.NewRGBA(img.Bounds())
+	draw.DrawMask(walls, walls.Bounds(), image.NewUniform(color.Black), image.Point{}, wallMask{img}, image.Point{}, draw.Over)
+	imported, err := ImportMazeImage(walls, ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.String() != maze.String() {
+		t.Errorf("unexpected maze from transparent image\n%s", imported)
+	}
+
+	// scan with specks of noise and margin
+	img = Draw(maze, yellow, blue, 20, 20, 2)
+	scan := image.NewRGBA(img.Bounds().Inset(-15))
+	draw.Draw(scan, scan.Bounds(), image.NewUniform(white), image.Point{}, draw.Src)
+	draw.Draw(scan, img.Bounds(), img, img.Bounds().Min, draw.Src)
+	r := rand.New(rand.NewSource(1))
+	b := scan.Bounds()
+	for i := 0; i < b.Dx()*b.Dy()/100; i++ {
+		x, y := b.Min.X+r.Intn(b.Dx()), b.Min.Y+r.Intn(b.Dy())
+		if r.Intn(2) == 0 {
+			scan.Set(x, y, blue)
+		} else {
+			scan.Set(x, y, white)
+		}
+	}
+	imported, err = ImportMazeImage(scan, ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.String() != maze.String() {
+		t.Errorf("unexpected maze from scan\n%s", imported)
+	}
+
+	path := make([]*cell, 0, w)
+	visited := make([]*cell, 0, w)
+	if !FindShortestPath(imported, imported.Begin(), imported.End(), &path, &visited) {
+		t.Error("path not found")
+	}
+
+	if _, err := ImportMazeImage(image.NewRGBA(image.Rect(0, 0, 10, 10)), ImportOptions{Threshold: 1}); err == nil {
+		t.Error("expected error for blank image")
+	}
+}
+
+// wallMask is opaque where image is dark
+type wallMask struct{ image.Image }
+
+func (m wallMask) ColorModel() color.Model { return color.AlphaModel }
+
+func (m wallMask) At(x, y int) color.Color {
+	if color.GrayModel.Convert(m.Image.At(x, y)).(color.Gray).Y < 128 {
+		return color.Opaque
+	}
+	return color.Transparent
+}
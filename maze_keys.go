@@ -0,0 +1,299 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+)
+
+// MaxKeys is number of key colors, keys and locks are colored 0..MaxKeys-1
+const MaxKeys = 8
+
+var keyColors = []color.Color{
+	color.RGBA{255, 0, 0, 255},
+	color.RGBA{0, 0, 255, 255},
+	color.RGBA{0, 153, 0, 255},
+	color.RGBA{255, 204, 0, 255},
+	color.RGBA{255, 102, 0, 255},
+	color.RGBA{102, 0, 153, 255},
+	color.RGBA{0, 153, 153, 255},
+	color.RGBA{153, 153, 153, 255},
+}
+
+type key struct {
+	c     *cell
+	color int
+}
+
+type lock struct {
+	c1, c2 *cell
+	color  int
+}
+
+// KeyAction is pickup of key or unlock of door on the way through maze,
+// door leads from Cell to To
+type KeyAction struct {
+	Cell   *cell
+	To     *cell
+	Key    int
+	Unlock bool
+}
+
+// AddKey puts key of color into cell c, key is picked up when solver
+// enters the cell and opens all locks of the same color
+func (m *Maze) AddKey(c *cell, color int) {
+	if color < 0 || color >= MaxKeys {
+		panic("key color out of range")
+	}
+	m.keys = append(m.keys, key{c, color})
+}
+
+// AddLock replaces wall or door between adjacent cells with door locked
+// by key of color, it can be passed both ways holding the key
+func (m *Maze) AddLock(c1, c2 *cell, color int) {
+	if color < 0 || color >= MaxKeys {
+		panic("lock color out of range")
+	}
+	if !m.isAdjacent(c1, c2) {
+		panic("cells not adjacent or same")
+	}
+	m.closeDoor(c1, c2)
+	m.locks = append(m.locks, lock{c1, c2, color})
+}
+
+// keysAt returns bit set of keys lying in cell c
+func (m *Maze) keysAt(c *cell) uint {
+	keys := uint(0)
+	for _, k := range m.keys {
+		if k.c == c {
+			keys |= 1 << uint(k.color)
+		}
+	}
+	return keys
+}
+
+// lockAt returns lock between c1 and c2, or nil if there is none
+func (m *Maze) lockAt(c1, c2 *cell) *lock {
+	for i, l := range m.locks {
+		if l.c1 == c1 && l.c2 == c2 || l.c1 == c2 && l.c2 == c1 {
+			return &m.locks[i]
+		}
+	}
+	return nil
+}
+
+// passable returns if c2 can be entered from c1 holding keys
+func (m *Maze) passable(c1, c2 *cell, keys uint) bool {
+	if isConnected(c1, c2) {
+		return true
+	}
+	l := m.lockAt(c1, c2)
+	return l != nil && keys&(1<<uint(l.color)) != 0
+}
+
+// keyState is cell with set of keys held when standing in it
+type keyState struct {
+	c    *cell
+	keys uint
+}
+
+// FindKeyPath finds shortest path from start to end where locked doors are
+// passed only holding key of their color. It searches over states of cell
+// and keys held, so path may visit cells several times. Path is from end to
+// start like other solvers, actions are pickups and unlocks in order
+func FindKeyPath(m *Maze, start, end *cell, path *[]*cell, actions *[]KeyAction) bool {
+	first := keyState{start, m.keysAt(start)}
+	parent := map[keyState]keyState{first: first}
+	q := []keyState{first}
+	for len(q) > 0 {
+		current := q[0]
+		q = q[1:]
+
+		if current.c == end {
+			states := []keyState{}
+			for s := current; s != first; s = parent[s] {
+				states = append(states, s)
+				*path = append(*path, s.c)
+			}
+			*path = append(*path, start)
+			*actions = append(*actions, m.keyActions(first, states)...)
+			return true
+		}
+
+		for _, c := range m.AdjacentCells(current.c, func(c *cell) bool {
+			return m.passable(current.c, c, current.keys)
+		}) {
+			next := keyState{c, current.keys | m.keysAt(c)}
+			if _, ok := parent[next]; ok {
+				continue
+			}
+			parent[next] = current
+			q = append(q, next)
+		}
+	}
+	return false
+}
+
+// keyActions returns pickups and unlocks along states, states are in
+// reverse order and don't include first one
+func (m *Maze) keyActions(first keyState, states []keyState) []KeyAction {
+	actions := []KeyAction{}
+	pickup := func(c *cell, held, keys uint) {
+		for color := 0; color < MaxKeys; color++ {
+			if bit := uint(1) << uint(color); keys&bit != 0 && held&bit == 0 {
+				actions = append(actions, KeyAction{Cell: c, Key: color})
+			}
+		}
+	}
+	pickup(first.c, 0, first.keys)
+	unlocked := map[*lock]bool{}
+	prev := first
+	for i := len(states) - 1; i >= 0; i-- {
+		s := states[i]
+		if l := m.lockAt(prev.c, s.c); l != nil && !isConnected(prev.c, s.c) && !unlocked[l] {
+			unlocked[l] = true
+			actions = append(actions, KeyAction{Cell: prev.c, To: s.c, Key: l.color, Unlock: true})
+		}
+		pickup(s.c, prev.keys, s.keys)
+		prev = s
+	}
+	return actions
+}
+
+// Keys returns generator which locks n doors on the way from begin to end
+// of perfect maze and hides key of each lock in branch which is reachable
+// only after passing previous lock, so keys must be collected in order.
+// Lock i has color i, places are picked by seed. Nil generator leaves
+// maze as it is
+func Keys(n int, seed int64, generator func(*Maze) (*Maze, []*cell)) func(*Maze) (*Maze, []*cell) {
+	return func(m *Maze) (*Maze, []*cell) {
+		if n < 0 || n > MaxKeys {
+			panic("number of keys out of range")
+		}
+		var genPath []*cell
+		if generator != nil {
+			m, genPath = generator(m)
+		}
+		if m.halted() {
+			return m, genPath
+		}
+		r := rand.New(rand.NewSource(seed))
+
+		// main path from begin to end
+		dist, parent := m.reach([]*cell{m.Begin()}, 0)
+		if _, ok := dist[m.End()]; !ok {
+			panic("end is not reachable")
+		}
+		way := make([]*cell, dist[m.End()]+1)
+		for c := m.End(); c != nil; c = parent[c] {
+			way[dist[c]] = c
+		}
+		if len(way) < 2*n+2 {
+			panic("path from begin to end is too short for locks")
+		}
+		off, _ := m.reach(way, 0)
+
+		// lock i is in i+1-th part of path, key i is placed among cells
+		// reachable with keys of previous locks only
+		seg := (len(way) - 1) / (n + 1)
+		reached := map[*cell]bool{}
+		for i := 0; i < n; i++ {
+			at := (i+1)*seg - r.Intn(seg/2+1)
+			m.AddLock(way[at], way[at+1], i)
+
+			dist, _ := m.reach([]*cell{m.Begin()}, 1<<uint(i)-1)
+			var far *cell
+			for x := range m.cells {
+				for _, c := range m.cells[x] {
+					if _, ok := dist[c]; !ok || reached[c] {
+						continue
+					}
+					reached[c] = true
+					// farthest from main path, so getting key is a detour
+					if far == nil || off[c] > off[far] || off[c] == off[far] && dist[c] > dist[far] {
+						far = c
+					}
+				}
+			}
+			if far == nil {
+				panic("no place for key")
+			}
+			m.AddKey(far, i)
+		}
+
+		path, actions := make([]*cell, 0, len(way)), []KeyAction{}
+		if !FindKeyPath(m, m.Begin(), m.End(), &path, &actions) {
+			panic("keys puzzle is not solvable")
+		}
+		return m, genPath
+	}
+}
+
+// reach returns distances of cells reachable from closest of cells
+// holding keys, parent of each cell is previous cell on the way
+func (m *Maze) reach(cells []*cell, keys uint) (map[*cell]int, map[*cell]*cell) {
+	dist := map[*cell]int{}
+	parent := map[*cell]*cell{}
+	q := []*cell{}
+	for _, c := range cells {
+		dist[c] = 0
+		q = append(q, c)
+	}
+	for len(q) > 0 {
+		current := q[0]
+		q = q[1:]
+		next := m.AdjacentCells(current, func(n *cell) bool {
+			return m.passable(current, n, keys)
+		})
+		for _, n := range next {
+			if _, ok := dist[n]; ok {
+				continue
+			}
+			dist[n] = dist[current] + 1
+			parent[n] = current
+			q = append(q, n)
+		}
+	}
+	return dist, parent
+}
+
+// drawKeys draws keys of cell c as colored diamonds and locks on its
+// doors as colored bars across them. Cell rect is shifted by offset on img
+func (m *Maze) drawKeys(img draw.Image, c *cell, offset image.Point, cw, ch, ww int) {
+	rect := cellRect(c, cw, ch).Sub(offset)
+	for _, k := range m.keys {
+		if k.c != c {
+			continue
+		}
+		r := rect.Inset(cw / 4)
+		cx, cy := (r.Min.X+r.Max.X)/2, (r.Min.Y+r.Max.Y)/2
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if abs(x-cx)*r.Dy()+abs(y-cy)*r.Dx() <= r.Dx()*r.Dy()/2 {
+					img.Set(x, y, keyColors[k.color])
+				}
+			}
+		}
+	}
+	for _, l := range m.locks {
+		other := l.c2
+		if l.c2 == c {
+			other = l.c1
+		} else if l.c1 != c {
+			continue
+		}
+		bar := rect
+		switch dx, dy := m.direction(c, other); {
+		case dx < 0:
+			bar.Max.X = bar.Min.X + ww
+		case dx > 0:
+			bar.Min.X = bar.Max.X - ww
+		case dy < 0:
+			bar.Max.Y = bar.Min.Y + ww
+		default:
+			bar.Min.Y = bar.Max.Y - ww
+		}
+		draw.Draw(img, bar, image.NewUniform(keyColors[l.color]), image.Point{}, draw.Src)
+	}
+}
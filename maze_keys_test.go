@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindKeyPath(t *testing.T) {
+	// key lies in dead end behind start, lock is on the way to end
+	maze, _ := NewMaze(4, 1, point{1, 0}, point{3, 0}, nil)
+	c := maze.cells
+	maze.RmWall(c[0][0], c[1][0])
+	maze.RmWall(c[1][0], c[2][0])
+	maze.AddLock(c[2][0], c[3][0], 1)
+
+	path, actions := []*cell{}, []KeyAction{}
+	if FindKeyPath(maze, maze.Begin(), maze.End(), &path, &actions) {
+		t.Fatal("passed lock without key")
+	}
+
+	maze.AddKey(c[0][0], 1)
+	if !FindKeyPath(maze, maze.Begin(), maze.End(), &path, &actions) {
+		t.Fatal("path not found")
+	}
+	expected := []*cell{c[3][0], c[2][0], c[1][0], c[0][0], c[1][0]}
+	if len(path) != len(expected) {
+		t.Fatalf("expected path of %d cells, got %d", len(expected), len(path))
+	}
+	for i := range path {
+		if path[i] != expected[i] {
+			t.Errorf("expected %v at %d, got %v", expected[i].point, i, path[i].point)
+		}
+	}
+	if len(actions) != 2 ||
+		actions[0] != (KeyAction{Cell: c[0][0], Key: 1}) ||
+		actions[1] != (KeyAction{Cell: c[2][0], To: c[3][0], Key: 1, Unlock: true}) {
+		t.Errorf("unexpected actions %v", actions)
+	}
+	if s := maze.String(); !strings.Contains(s, "| 1   S     1 E |") {
+		t.Errorf("unexpected maze\n%s", s)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	w, h, n := 20, 20, 3
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, Keys(n, 1, DFS(NewStack(), 1)))
+	maze.ResetVisitedCells()
+
+	if len(maze.keys) != n || len(maze.locks) != n {
+		t.Fatalf("expected %d keys and locks, got %d and %d", n, len(maze.keys), len(maze.locks))
+	}
+	path := make([]*cell, 0, w)
+	visited := make([]*cell, 0, w)
+	if FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited) {
+		t.Error("end reachable without keys")
+	}
+	maze.ResetVisitedCells()
+
+	path, actions := []*cell{}, []KeyAction{}
+	if !FindKeyPath(maze, maze.Begin(), maze.End(), &path, &actions) {
+		t.Fatal("keys puzzle not solvable")
+	}
+	// keys are picked and used in order
+	if len(actions) != 2*n {
+		t.Fatalf("expected %d actions, got %v", 2*n, actions)
+	}
+	for i, a := range actions {
+		if a.Key != i/2 || a.Unlock != (i%2 == 1) {
+			t.Errorf("unexpected action %d %v", i, a)
+		}
+	}
+	for i := 1; i < len(path); i++ {
+		if !maze.passable(path[i], path[i-1], ^uint(0)) {
+			t.Errorf("path is broken at %v", path[i].point)
+		}
+	}
+
+	// same seed, same puzzle
+	again, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, Keys(n, 1, DFS(NewStack(), 1)))
+	if again.String() != maze.String() {
+		t.Error("expected same puzzle for same seed")
+	}
+
+	img := Draw(maze, white, black, 20, 20, 2)
+	for _, k := range maze.keys {
+		if col := img.At(k.c.x*20+10, k.c.y*20+10); !sameColor(col, keyColors[k.color]) {
+			t.Errorf("expected key at %v, got %v", k.c.point, col)
+		}
+	}
+}
+
+func TestKeysWithoutGenerator(t *testing.T) {
+	// walls of maze are kept, there is no way to lock
+	defer func() {
+		if r := recover(); r != "end is not reachable" {
+			t.Errorf("expected panic of walled maze, got %v", r)
+		}
+	}()
+	NewMaze(3, 1, point{0, 0}, point{2, 0}, Keys(1, 1, nil))
+}
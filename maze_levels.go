@@ -0,0 +1,157 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+)
+
+// Layered returns generator which turns maze into levels of square grids,
+// maze rows are split into levels of equal height, level z takes rows from
+// z*h/levels. Each cell is adjacent to cells right above and below it on
+// next and previous levels, doors between levels are stairs
+func Layered(levels int, generator func(*Maze) (*Maze, []*cell)) func(*Maze) (*Maze, []*cell) {
+	return func(m *Maze) (*Maze, []*cell) {
+		if levels < 1 || m.h%levels != 0 {
+			panic("h should be multiple of levels")
+		}
+		return withTopology(layeredGrid{levels, m.h / levels}, generator)(m)
+	}
+}
+
+// layeredGrid is square grid of levels stacked in maze rows
+type layeredGrid struct {
+	levels int
+	lh     int // level height
+}
+
+func (layeredGrid) planar() {}
+
+func (g layeredGrid) Neighbors(m *Maze, p point) []point {
+	points := make([]point, 0, 6)
+	z := p.y / g.lh
+	for _, n := range []point{{p.x - 1, p.y}, {p.x + 1, p.y}, {p.x, p.y - 1}, {p.x, p.y + 1}} {
+		if m.inside(n) && n.y/g.lh == z {
+			points = append(points, n)
+		}
+	}
+	// stairs
+	for _, n := range []point{{p.x, p.y - g.lh}, {p.x, p.y + g.lh}} {
+		if m.inside(n) {
+			points = append(points, n)
+		}
+	}
+	return points
+}
+
+func (g layeredGrid) Walls(m *Maze, p point, size float64) []wall {
+	walls := squareGrid{}.Walls(m, p, size)
+	// level edges
+	walls[0].outer = p.y%g.lh == 0
+	walls[2].outer = p.y%g.lh == g.lh-1
+	return walls
+}
+
+func (layeredGrid) ImageSize(m *Maze, size float64) (float64, float64) {
+	return float64(m.w) * size, float64(m.h) * size
+}
+
+// stairs returns if cell has stairs to level above (z+1) and below
+func (g layeredGrid) stairs(c *cell) (up, down bool) {
+	for _, l := range c.links {
+		if l.x != c.x {
+			continue
+		}
+		up = up || l.y == c.y+g.lh
+		down = down || l.y == c.y-g.lh
+	}
+	return up, down
+}
+
+// drawStairs marks stairs of cells, stairs up are triangle pointing up in
+// left half of cell and stairs down pointing down in right half
+func (g layeredGrid) drawStairs(img draw.Image, cells []*cell, offset image.Point, mark color.Color, cw, ch, ww int) {
+	for _, c := range cells {
+		up, down := g.stairs(c)
+		rect := cellRect(c, cw, ch).Sub(offset).Inset(ww + cw/8)
+		half := rect.Dx() / 2
+		if up {
+			fillTriangle(img, image.Rect(rect.Min.X, rect.Min.Y, rect.Min.X+half, rect.Max.Y), point{0, -1}, mark)
+		}
+		if down {
+			fillTriangle(img, image.Rect(rect.Max.X-half, rect.Min.Y, rect.Max.X, rect.Max.Y), point{0, 1}, mark)
+		}
+	}
+}
+
+// fillTriangle fills isosceles triangle inside r pointing in direction dir
+func fillTriangle(img draw.Image, r image.Rectangle, dir point, c color.Color) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// width of triangle grows from apex to base
+			along := (float64(y-r.Min.Y) + 0.5) / float64(r.Dy())
+			across, size := float64(x)+0.5-float64(r.Min.X+r.Max.X)/2, float64(r.Dx())
+			if dir.x != 0 {
+				along = (float64(x-r.Min.X) + 0.5) / float64(r.Dx())
+				across, size = float64(y)+0.5-float64(r.Min.Y+r.Max.Y)/2, float64(r.Dy())
+			}
+			if dir.x > 0 || dir.y > 0 {
+				along = 1 - along
+			}
+			if across < 0 {
+				across = -across
+			}
+			if across < along*size/2 {
+				img.Set(x, y, c)
+			}
+		}
+	}
+}
+
+// DrawLevel returns image of level z of layered maze with stairs marked
+func DrawLevel(m *Maze, z int, fill, border color.Color, cw, ch, ww int) *image.Paletted {
+	g, ok := m.topology.(layeredGrid)
+	if !ok {
+		g = layeredGrid{1, m.h}
+	}
+	if z < 0 || z >= g.levels {
+		panic("level should be inside maze")
+	}
+	img := image.NewPaletted(image.Rect(0, 0, m.w*cw, g.lh*ch), palette.WebSafe)
+	offset := image.Pt(0, z*g.lh*ch)
+	cells := make([]*cell, 0, m.w*g.lh)
+	for x := range m.cells {
+		cells = append(cells, m.cells[x][z*g.lh:(z+1)*g.lh]...)
+	}
+	for _, c := range cells {
+		rect := cellRect(c, cw, ch).Sub(offset)
+		DrawCell(c, img.SubImage(rect).(*image.Paletted), fill, border, cw, ch, ww)
+		m.drawMarks(img, c, offset, border, cw, ch, ww)
+	}
+	g.drawStairs(img, cells, offset, border, cw, ch, ww)
+	return img
+}
+
+// DrawLevels returns sheet with images of all levels of layered maze, cols
+// levels in a row separated by gap pixels
+func DrawLevels(m *Maze, cols, gap int, fill, border color.Color, cw, ch, ww int) *image.Paletted {
+	levels := 1
+	if g, ok := m.topology.(layeredGrid); ok {
+		levels = g.levels
+	}
+	if cols < 1 || cols > levels {
+		cols = levels
+	}
+	rows := (levels + cols - 1) / cols
+	lw, lh := m.w*cw, m.h/levels*ch
+	r := image.Rect(0, 0, cols*lw+(cols-1)*gap, rows*lh+(rows-1)*gap)
+	sheet := image.NewPaletted(r, palette.WebSafe)
+	draw.Draw(sheet, r, image.NewUniform(fill), image.Point{}, draw.Src)
+	for z := 0; z < levels; z++ {
+		at := image.Pt(z%cols*(lw+gap), z/cols*(lh+gap))
+		level := DrawLevel(m, z, fill, border, cw, ch, ww)
+		draw.Draw(sheet, level.Bounds().Add(at), level, image.Point{}, draw.Src)
+	}
+	return sheet
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLayeredMaze(t *testing.T) {
+	w, lh, levels := 6, 5, 3
+	cw, ch, ww := 20, 20, 2
+	maze, _ := NewMaze(w, lh*levels, point{0, 0}, point{w - 1, lh*levels - 1},
+		Layered(levels, DFS(NewStack(), 1)))
+	g := maze.topology.(layeredGrid)
+
+	cells, doors, stairs := 0, 0, 0
+	for x := range maze.cells {
+		for _, c := range maze.cells[x] {
+			cells++
+			// no planar doors between levels
+			if c.y%lh == 0 && c.up || c.y%lh == lh-1 && c.down {
+				t.Errorf("cell %v has door to other level", c.point)
+			}
+			for _, n := range maze.AdjacentCells(c, func(*cell) bool { return true }) {
+				if isConnected(c, n) {
+					doors++
+				}
+			}
+			if up, _ := g.stairs(c); up {
+				stairs++
+			}
+		}
+	}
+	if doors/2 != cells-1 {
+		t.Errorf("expected %d doors for %d cells, got %d", cells-1, cells, doors/2)
+	}
+	if stairs == 0 {
+		t.Error("expected stairs between levels")
+	}
+	maze.ResetVisitedCells()
+
+	path := make([]*cell, 0, w)
+	visited := make([]*cell, 0, w)
+	if !FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited) {
+		t.Fatal("path not found")
+	}
+	maze.ResetVisitedCells()
+
+	level := DrawLevel(maze, 1, white, black, cw, ch, ww)
+	if b := level.Bounds(); b.Dx() != w*cw || b.Dy() != lh*ch {
+		t.Errorf("unexpected level size %v", b)
+	}
+	sheet := DrawLevels(maze, 2, 10, white, black, cw, ch, ww)
+	if b := sheet.Bounds(); b.Dx() != 2*w*cw+10 || b.Dy() != 2*lh*ch+10 {
+		t.Errorf("unexpected sheet size %v", b)
+	}
+	if f, err := os.Create(filepath.Join(t.TempDir(), "maze-levels.png")); err == nil {
+		if err = png.Encode(f, sheet); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// Masked returns generator which removes cells marked in mask from maze
+// and then runs generator, mask is indexed as mask[x][y]. Masked cells are
+// skipped by AdjacentCells so generators and solvers never enter them
+func Masked(mask [][]bool, generator func(*Maze) (*Maze, []*cell)) func(*Maze) (*Maze, []*cell) {
+	return func(m *Maze) (*Maze, []*cell) {
+		for x := range m.cells {
+			for y := range m.cells[x] {
+				if x < len(mask) && y < len(mask[x]) && mask[x][y] {
+					m.cells[x][y].masked = true
+				}
+			}
+		}
+		if m.Begin().masked || m.End().masked {
+			panic("start and end point should not be masked")
+		}
+		if generator == nil {
+			return m, nil
+		}
+		return generator(m)
+	}
+}
+
+// ImageMask returns w x h mask from image, image is scaled to maze size
+// and cells over dark pixels are masked
+func ImageMask(img image.Image, w, h int) [][]bool {
+	b := img.Bounds()
+	mask := make([][]bool, w)
+	for x := range mask {
+		mask[x] = make([]bool, h)
+		for y := range mask[x] {
+			// sample center of cell
+			px := b.Min.X + (2*x+1)*b.Dx()/(2*w)
+			py := b.Min.Y + (2*y+1)*b.Dy()/(2*h)
+			gray := color.GrayModel.Convert(img.At(px, py)).(color.Gray)
+			_, _, _, a := img.At(px, py).RGBA()
+			// transparent pixels are background, not black
+			mask[x][y] = a > 0x7fff && gray.Y < 0x80
+		}
+	}
+	return mask
+}
+
+// TextMask returns mask from text lines, '#' marks cell which is not part
+// of maze, e.g.
+//
+//	##..##
+//	#....#
+//	##..##
+//
+// Shorter lines are padded with cells
+func TextMask(text string) [][]bool {
+	lines := strings.Split(strings.Trim(text, "\n"), "\n")
+	w := 0
+	for _, l := range lines {
+		if len(l) > w {
+			w = len(l)
+		}
+	}
+	mask := make([][]bool, w)
+	for x := range mask {
+		mask[x] = make([]bool, len(lines))
+		for y, l := range lines {
+			mask[x][y] = x < len(l) && l[x] == '#'
+		}
+	}
+	return mask
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestMaskedMaze(t *testing.T) {
+	mask := TextMask(`
+..........
+.##....##.
+.##....##.
+..........
+####..####
+####..####
+`)
+	w, h := len(mask), len(mask[0])
+	maze, _ := NewMaze(w, h, point{0, 0}, point{5, 5}, Masked(mask, DFS(NewStack(), 1)))
+
+	for x := range maze.cells {
+		for y, c := range maze.cells[x] {
+			if c.masked != mask[x][y] {
+				t.Fatalf("cell %v masked %v, expected %v", c.point, c.masked, mask[x][y])
+			}
+			if c.masked && (c.left || c.up || c.right || c.down) {
+				t.Errorf("masked cell %v has doors", c.point)
+			}
+			// region is connected, so generator visits every cell
+			if !c.masked && !c.visited {
+				t.Errorf("cell %v not visited", c.point)
+			}
+		}
+	}
+	maze.ResetVisitedCells()
+
+	path := make([]*cell, 0, w)
+	visited := make([]*cell, 0, w)
+	if !FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited) {
+		t.Fatal("path not found")
+	}
+	for _, c := range visited {
+		if c.masked {
+			t.Errorf("solver entered masked cell %v", c.point)
+		}
+	}
+
+	lines := strings.Split(maze.String(), "\n")
+	// last row has only 2 cells in the middle
+	if expected := "                +---+---+                "; lines[len(lines)-2] != expected {
+		t.Errorf("expected last line %q, got %q", expected, lines[len(lines)-2])
+	}
+
+	img := Draw(maze, white, black, 10, 10, 2)
+	if c := img.At(1, 41); !sameColor(c, white) {
+		t.Errorf("expected masked cell blank, got %v", c)
+	}
+}
+
+func TestImageMask(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			img.Set(x, y, color.White)
+			if x >= 20 {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	mask := ImageMask(img, 4, 2)
+	for x := range mask {
+		for y := range mask[x] {
+			if mask[x][y] != (x >= 2) {
+				t.Errorf("unexpected mask at %d,%d: %v", x, y, mask[x][y])
+			}
+		}
+	}
+}
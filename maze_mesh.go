@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// MeshConfig sets sizes of 3D model, cells are CellSize wide, walls are
+// boxes WallThickness wide and WallHeight high standing on floor plate
+// FloorThickness thick
+type MeshConfig struct {
+	CellSize       float64
+	WallHeight     float64
+	WallThickness  float64
+	FloorThickness float64
+}
+
+type vec3 struct {
+	x, y, z float64
+}
+
+func (a vec3) sub(b vec3) vec3 {
+	return vec3{a.x - b.x, a.y - b.y, a.z - b.z}
+}
+
+func (a vec3) cross(b vec3) vec3 {
+	return vec3{a.y*b.z - a.z*b.y, a.z*b.x - a.x*b.z, a.x*b.y - a.y*b.x}
+}
+
+// Mesh is 3D model of maze made of triangles, vertices of each triangle
+// are counter clockwise seen from outside
+type Mesh struct {
+	Triangles [][3]vec3
+}
+
+// NewMesh returns model of maze walls standing on floor plate, walls in
+// line are merged to single box. Maze y axis is flipped so model is not
+// mirrored
+func NewMesh(m *Maze, cfg MeshConfig) *Mesh {
+	if cfg.CellSize <= 0 || cfg.WallHeight <= 0 || cfg.WallThickness <= 0 || cfg.FloorThickness < 0 {
+		panic("mesh sizes should be positive")
+	}
+	mesh := &Mesh{}
+	w, h := m.topology.ImageSize(m, cfg.CellSize)
+	if cfg.FloorThickness > 0 {
+		mesh.box([4]vec{{0, h}, {w, h}, {w, 0}, {0, 0}}, -cfg.FloorThickness, 0, h)
+	}
+	t := cfg.WallThickness / 2
+	for _, s := range mergeSegments(m, cfg.CellSize) {
+		a, b := s[0], s[1]
+		l := math.Hypot(b.x-a.x, b.y-a.y)
+		// along and across wall, ends are extended to close corners
+		d := vec{(b.x - a.x) / l * t, (b.y - a.y) / l * t}
+		n := vec{-d.y, d.x}
+		mesh.box([4]vec{
+			{a.x - d.x - n.x, a.y - d.y - n.y},
+			{b.x + d.x - n.x, b.y + d.y - n.y},
+			{b.x + d.x + n.x, b.y + d.y + n.y},
+			{a.x - d.x + n.x, a.y - d.y + n.y},
+		}, 0, cfg.WallHeight, h)
+	}
+	return mesh
+}
+
+// box adds box with base of 4 corners in image coordinates from height
+// z0 to z1, y is flipped inside image of height h
+func (mesh *Mesh) box(base [4]vec, z0, z1, h float64) {
+	var lo, hi [4]vec3
+	for i, p := range base {
+		lo[i] = vec3{p.x, h - p.y, z0}
+		hi[i] = vec3{p.x, h - p.y, z1}
+	}
+	// flipping y turns clockwise base in image counter clockwise
+	if signedArea(lo) < 0 {
+		lo[1], lo[3] = lo[3], lo[1]
+		hi[1], hi[3] = hi[3], hi[1]
+	}
+	quad := func(a, b, c, d vec3) {
+		mesh.Triangles = append(mesh.Triangles, [3]vec3{a, b, c}, [3]vec3{a, c, d})
+	}
+	quad(lo[0], lo[3], lo[2], lo[1])
+	quad(hi[0], hi[1], hi[2], hi[3])
+	for i := 0; i < 4; i++ {
+		j := (i + 1) % 4
+		quad(lo[i], lo[j], hi[j], hi[i])
+	}
+}
+
+func signedArea(p [4]vec3) float64 {
+	a := 0.0
+	for i := range p {
+		j := (i + 1) % len(p)
+		a += p[i].x*p[j].y - p[j].x*p[i].y
+	}
+	return a / 2
+}
+
+// mergeSegments returns closed walls of maze as segments, segments on
+// the same line which touch or overlap are joined
+func mergeSegments(m *Maze, size float64) [][2]vec {
+	const eps = 1e-6
+	type line struct{ dx, dy, offset int64 }
+	round := func(f float64) int64 { return int64(math.Round(f / eps)) }
+
+	keys := []line{}
+	groups := map[line][][2]vec{}
+	closedWalls(m, size, func(points []vec) {
+		for i := 1; i < len(points); i++ {
+			a, b := points[i-1], points[i]
+			if b.x < a.x-eps || math.Abs(b.x-a.x) <= eps && b.y < a.y {
+				a, b = b, a
+			}
+			l := math.Hypot(b.x-a.x, b.y-a.y)
+			if l <= eps {
+				continue
+			}
+			dx, dy := (b.x-a.x)/l, (b.y-a.y)/l
+			k := line{round(dx), round(dy), round(dx*a.y - dy*a.x)}
+			if _, ok := groups[k]; !ok {
+				keys = append(keys, k)
+			}
+			groups[k] = append(groups[k], [2]vec{a, b})
+		}
+	})
+
+	merged := [][2]vec{}
+	for _, k := range keys {
+		segs := groups[k]
+		dx, dy := float64(k.dx)*eps, float64(k.dy)*eps
+		pos := func(p vec) float64 { return p.x*dx + p.y*dy }
+		sort.Slice(segs, func(i, j int) bool { return pos(segs[i][0]) < pos(segs[j][0]) })
+		cur := segs[0]
+		for _, s := range segs[1:] {
+			if pos(s[0]) <= pos(cur[1])+eps {
+				if pos(s[1]) > pos(cur[1]) {
+					cur[1] = s[1]
+				}
+				continue
+			}
+			merged = append(merged, cur)
+			cur = s
+		}
+		merged = append(merged, cur)
+	}
+	return merged
+}
+
+func normal(t [3]vec3) vec3 {
+	n := t[1].sub(t[0]).cross(t[2].sub(t[0]))
+	l := math.Sqrt(n.x*n.x + n.y*n.y + n.z*n.z)
+	if l == 0 {
+		return n
+	}
+	return vec3{n.x / l, n.y / l, n.z / l}
+}
+
+// WriteSTL writes mesh as ASCII STL solid
+func (mesh *Mesh) WriteSTL(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "solid maze")
+	for _, t := range mesh.Triangles {
+		n := normal(t)
+		fmt.Fprintf(b, "  facet normal %g %g %g\n    outer loop\n", n.x, n.y, n.z)
+		for _, v := range t {
+			fmt.Fprintf(b, "      vertex %g %g %g\n", v.x, v.y, v.z)
+		}
+		fmt.Fprintln(b, "    endloop\n  endfacet")
+	}
+	fmt.Fprintln(b, "endsolid maze")
+	return b.Flush()
+}
+
+// WriteBinarySTL writes mesh as binary STL, it's much smaller than ASCII
+func (mesh *Mesh) WriteBinarySTL(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	header := [80]byte{}
+	copy(header[:], "maze")
+	b.Write(header[:])
+	binary.Write(b, binary.LittleEndian, uint32(len(mesh.Triangles)))
+	for _, t := range mesh.Triangles {
+		n := normal(t)
+		data := [12]float32{float32(n.x), float32(n.y), float32(n.z)}
+		for i, v := range t {
+			data[3+3*i], data[4+3*i], data[5+3*i] = float32(v.x), float32(v.y), float32(v.z)
+		}
+		binary.Write(b, binary.LittleEndian, data)
+		// attribute byte count
+		binary.Write(b, binary.LittleEndian, uint16(0))
+	}
+	return b.Flush()
+}
+
+// WriteOBJ writes mesh as Wavefront OBJ, shared vertices are written once
+func (mesh *Mesh) WriteOBJ(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	index := map[vec3]int{}
+	faces := make([][3]int, 0, len(mesh.Triangles))
+	for _, t := range mesh.Triangles {
+		f := [3]int{}
+		for i, v := range t {
+			if _, ok := index[v]; !ok {
+				index[v] = len(index) + 1
+				fmt.Fprintf(b, "v %g %g %g\n", v.x, v.y, v.z)
+			}
+			f[i] = index[v]
+		}
+		faces = append(faces, f)
+	}
+	for _, f := range faces {
+		fmt.Fprintf(b, "f %d %d %d\n", f[0], f[1], f[2])
+	}
+	return b.Flush()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+// volume returns volume enclosed by mesh, negative if triangles face in
+func volume(mesh *Mesh) float64 {
+	v := 0.0
+	for _, t := range mesh.Triangles {
+		c := t[1].cross(t[2])
+		v += t[0].x*c.x + t[0].y*c.y + t[0].z*c.z
+	}
+	return v / 6
+}
+
+func TestNewMesh(t *testing.T) {
+	maze, _ := NewMaze(3, 1, point{0, 0}, point{2, 0}, nil)
+	maze.RmWall(maze.cells[0][0], maze.cells[1][0])
+	maze.RmWall(maze.cells[1][0], maze.cells[2][0])
+	cfg := MeshConfig{CellSize: 10, WallHeight: 5, WallThickness: 2, FloorThickness: 1}
+	mesh := NewMesh(maze, cfg)
+
+	// floor and 4 merged walls
+	if len(mesh.Triangles) != 5*12 {
+		t.Errorf("expected %d triangles, got %d", 5*12, len(mesh.Triangles))
+	}
+	// floor 30x10x1, long walls 32x2x5, short walls 12x2x5
+	if v := volume(mesh); math.Abs(v-1180) > 1e-6 {
+		t.Errorf("expected volume 1180, got %v", v)
+	}
+	// closed surface, every edge is used once in each direction
+	edges := map[[2]vec3]int{}
+	for _, tr := range mesh.Triangles {
+		for i := range tr {
+			edges[[2]vec3{tr[i], tr[(i+1)%3]}]++
+		}
+	}
+	for e, n := range edges {
+		if edges[[2]vec3{e[1], e[0]}] != n {
+			t.Errorf("open edge %v", e)
+		}
+	}
+}
+
+func TestMeshWriters(t *testing.T) {
+	w, h := 8, 8
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 1))
+	mesh := NewMesh(maze, MeshConfig{CellSize: 10, WallHeight: 10, WallThickness: 1, FloorThickness: 2})
+	if v := volume(mesh); v <= 0 {
+		t.Errorf("expected positive volume, got %v", v)
+	}
+	if cells := w*h*4*12 + 12; len(mesh.Triangles) >= cells/2 {
+		t.Errorf("expected merged walls, got %d triangles", len(mesh.Triangles))
+	}
+
+	out := &bytes.Buffer{}
+	if err := mesh.WriteBinarySTL(out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 84+50*len(mesh.Triangles) {
+		t.Errorf("unexpected binary stl size %d", out.Len())
+	}
+
+	out.Reset()
+	if err := mesh.WriteSTL(out); err != nil {
+		t.Fatal(err)
+	}
+	if s := out.String(); !strings.HasPrefix(s, "solid maze\n") ||
+		strings.Count(s, "facet normal") != len(mesh.Triangles) {
+		t.Error("unexpected ascii stl")
+	}
+
+	out.Reset()
+	if err := mesh.WriteOBJ(out); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "\nf "); n != len(mesh.Triangles) {
+		t.Errorf("expected %d faces, got %d", len(mesh.Triangles), n)
+	}
+
+	// other topologies
+	hex, _ := NewMaze(6, 6, point{0, 0}, point{5, 5}, Hexagonal(DFS(NewStack(), 1)))
+	if v := volume(NewMesh(hex, MeshConfig{10, 5, 1, 1})); v <= 0 {
+		t.Errorf("expected positive volume, got %v", v)
+	}
+}
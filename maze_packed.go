@@ -0,0 +1,285 @@
+package main
+
+import "math/rand"
+
+// pairs is packed array of 2 bit values
+type pairs []uint64
+
+func newPairs(n int) pairs {
+	return make(pairs, (n+31)/32)
+}
+
+func (p pairs) get(i int) uint {
+	return uint(p[i/32]>>(uint(i%32)*2)) & 3
+}
+
+func (p pairs) set(i int, v uint) {
+	s := uint(i%32) * 2
+	p[i/32] = p[i/32]&^(3<<s) | uint64(v&3)<<s
+}
+
+// Bitset is packed set of cell indexes, solvers use it to mark visited
+// cells of PackedMaze
+type Bitset []uint64
+
+// NewBitset returns empty set for indexes 0..n-1
+func NewBitset(n int) Bitset {
+	return make(Bitset, (n+63)/64)
+}
+
+func (b Bitset) Get(i int) bool {
+	return b[i/64]&(1<<uint(i%64)) != 0
+}
+
+func (b Bitset) Set(i int) {
+	b[i/64] |= 1 << uint(i%64)
+}
+
+// Clear removes all indexes from set
+func (b Bitset) Clear() {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// doors of packed cell, left and up doors are stored by neighbours
+const (
+	doorRight = 1 << iota
+	doorDown
+)
+
+// PackedMaze is square maze stored in 2 bits per cell, cell is referred
+// by index y*w+x. It's meant for mazes too big for Maze, 10000x10000 maze
+// takes 25MB, PackedDFS needs 38MB more while carving it
+type PackedMaze struct {
+	w, h        int
+	entry, exit int
+	doors       pairs
+}
+
+// NewPackedMaze returns maze with all walls, generator carves it
+func NewPackedMaze(w, h int, entry, exit point, generator func(*PackedMaze) *PackedMaze) *PackedMaze {
+	if w < 1 || h < 1 {
+		panic("w, h should be > 0")
+	}
+	for _, p := range []point{entry, exit} {
+		if p.x > w-1 || p.x < 0 || p.y > h-1 || p.y < 0 {
+			panic("start and end point should be inside maze")
+		}
+	}
+	m := &PackedMaze{w: w, h: h, doors: newPairs(w * h)}
+	m.entry, m.exit = m.Index(entry), m.Index(exit)
+	if generator == nil {
+		return m
+	}
+	return generator(m)
+}
+
+// Len returns number of cells
+func (m *PackedMaze) Len() int {
+	return m.w * m.h
+}
+
+// Index returns index of cell at p
+func (m *PackedMaze) Index(p point) int {
+	return p.y*m.w + p.x
+}
+
+// Point returns position of cell i
+func (m *PackedMaze) Point(i int) point {
+	return point{i % m.w, i / m.w}
+}
+
+func (m *PackedMaze) Begin() int {
+	return m.entry
+}
+
+func (m *PackedMaze) End() int {
+	return m.exit
+}
+
+// Neighbors appends cells next to cell i to buf, buf of capacity 4 is
+// never reallocated
+func (m *PackedMaze) Neighbors(i int, buf []int) []int {
+	x, y := i%m.w, i/m.w
+	if x > 0 {
+		buf = append(buf, i-1)
+	}
+	if x < m.w-1 {
+		buf = append(buf, i+1)
+	}
+	if y > 0 {
+		buf = append(buf, i-m.w)
+	}
+	if y < m.h-1 {
+		buf = append(buf, i+m.w)
+	}
+	return buf
+}
+
+// Connected returns if there is door between neighbour cells i and j
+func (m *PackedMaze) Connected(i, j int) bool {
+	if i > j {
+		i, j = j, i
+	}
+	if j == i+1 && j%m.w != 0 {
+		return m.doors.get(i)&doorRight != 0
+	}
+	if j == i+m.w {
+		return m.doors.get(i)&doorDown != 0
+	}
+	return false
+}
+
+// RmWall removes wall between neighbour cells i and j
+func (m *PackedMaze) RmWall(i, j int) {
+	if i > j {
+		i, j = j, i
+	}
+	switch {
+	case j == i+1 && j%m.w != 0:
+		m.doors.set(i, m.doors.get(i)|doorRight)
+	case j == i+m.w:
+		m.doors.set(i, m.doors.get(i)|doorDown)
+	default:
+		panic("cells not adjacent or same")
+	}
+}
+
+// PackedDFS returns generator carving packed maze like DFS. Instead of
+// stack it keeps way back of each cell in 2 bits, so it needs 3 bits per
+// cell however long branches are
+func PackedDFS(seed int64) func(*PackedMaze) *PackedMaze {
+	return func(m *PackedMaze) *PackedMaze {
+		r := rand.New(rand.NewSource(seed))
+		visited := NewBitset(m.Len())
+		// direction to cell the cell was carved from
+		back := newPairs(m.Len())
+		buf := make([]int, 0, 4)
+		current := m.Begin()
+		visited.Set(current)
+		for {
+			// filter neighbours in place
+			unvisited := buf[:0]
+			for _, n := range m.Neighbors(current, buf[:0]) {
+				if !visited.Get(n) {
+					unvisited = append(unvisited, n)
+				}
+			}
+			if len(unvisited) > 0 {
+				next := unvisited[r.Intn(len(unvisited))]
+				m.RmWall(current, next)
+				back.set(next, m.dir(next, current))
+				current = next
+				visited.Set(current)
+			} else if current != m.Begin() {
+				current = m.step(current, back.get(current))
+			} else {
+				break
+			}
+		}
+		return m
+	}
+}
+
+// FindPackedShortestPath is BFS search of packed maze, visited set is
+// owned by caller and may be reused after Clear, nil allocates new one.
+// Way back to start is kept in 2 bits per cell. Path is from end to start
+func FindPackedShortestPath(m *PackedMaze, start, end int, visited Bitset) ([]int, bool) {
+	if visited == nil {
+		visited = NewBitset(m.Len())
+	}
+	// index of neighbour cell the cell was reached from
+	back := newPairs(m.Len())
+	q := make([]int32, 0, 1024)
+	buf := make([]int, 0, 4)
+	visited.Set(start)
+	q = append(q, int32(start))
+	for head := 0; head < len(q); head++ {
+		current := int(q[head])
+		if current == end {
+			path := []int{}
+			for c := end; c != start; {
+				path = append(path, c)
+				c = m.step(c, back.get(c))
+			}
+			return append(path, start), true
+		}
+		for _, n := range m.Neighbors(current, buf[:0]) {
+			if visited.Get(n) || !m.Connected(current, n) {
+				continue
+			}
+			visited.Set(n)
+			back.set(n, m.dir(n, current))
+			q = append(q, int32(n))
+		}
+		// drop processed part of queue
+		if head > 1024 && head > len(q)/2 {
+			q = append(q[:0], q[head+1:]...)
+			head = -1
+		}
+	}
+	return nil, false
+}
+
+// dir returns direction from cell i to neighbour j, 0 left, 1 right, 2 up
+// and 3 down
+func (m *PackedMaze) dir(i, j int) uint {
+	switch j - i {
+	case -1:
+		return 0
+	case 1:
+		return 1
+	case -m.w:
+		return 2
+	}
+	return 3
+}
+
+// step returns neighbour of cell i in direction d
+func (m *PackedMaze) step(i int, d uint) int {
+	return i + [4]int{-1, 1, -m.w, m.w}[d]
+}
+
+// Pack returns packed copy of square maze, packed maze has no doors
+// across wrapped edges and no masked cells
+func Pack(m *Maze) *PackedMaze {
+	g, ok := m.topology.(squareGrid)
+	if !ok {
+		panic("only square mazes can be packed")
+	}
+	if h, v := g.wraps(m); h || v {
+		panic("wrapped mazes can't be packed")
+	}
+	p := NewPackedMaze(m.w, m.h, m.entry, m.exit, nil)
+	for x := range m.cells {
+		for y, c := range m.cells[x] {
+			if c.masked {
+				panic("masked mazes can't be packed")
+			}
+			if c.right && x < m.w-1 {
+				p.RmWall(p.Index(c.point), p.Index(point{x + 1, y}))
+			}
+			if c.down && y < m.h-1 {
+				p.RmWall(p.Index(c.point), p.Index(point{x, y + 1}))
+			}
+		}
+	}
+	return p
+}
+
+// Unpack returns Maze of packed maze so it can be drawn, it's as big as
+// any Maze
+func (m *PackedMaze) Unpack() *Maze {
+	u, _ := NewMaze(m.w, m.h, m.Point(m.entry), m.Point(m.exit), nil)
+	for i := 0; i < m.Len(); i++ {
+		p := m.Point(i)
+		if p.x < m.w-1 && m.Connected(i, i+1) {
+			u.RmWall(u.cells[p.x][p.y], u.cells[p.x+1][p.y])
+		}
+		if p.y < m.h-1 && m.Connected(i, i+m.w) {
+			u.RmWall(u.cells[p.x][p.y], u.cells[p.x][p.y+1])
+		}
+	}
+	return u
+}
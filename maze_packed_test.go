@@ -0,0 +1,125 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestPackedMaze(t *testing.T) {
+	w, h := 40, 30
+	packed := NewPackedMaze(w, h, point{0, 0}, point{w - 1, h - 1}, PackedDFS(1))
+
+	// perfect maze has one door less than cells
+	doors := 0
+	for i := 0; i < packed.Len(); i++ {
+		for _, n := range packed.Neighbors(i, nil) {
+			if n > i && packed.Connected(i, n) {
+				doors++
+			}
+		}
+	}
+	if doors != w*h-1 {
+		t.Errorf("expected %d doors, got %d", w*h-1, doors)
+	}
+
+	visited := NewBitset(packed.Len())
+	path, found := FindPackedShortestPath(packed, packed.Begin(), packed.End(), visited)
+	if !found || path[0] != packed.End() || path[len(path)-1] != packed.Begin() {
+		t.Fatal("path not found")
+	}
+	for i := 1; i < len(path); i++ {
+		if !packed.Connected(path[i-1], path[i]) {
+			t.Errorf("path is broken at %v", packed.Point(path[i]))
+		}
+	}
+
+	maze := packed.Unpack()
+	cells := make([]*cell, 0, w)
+	visitedCells := make([]*cell, 0, w)
+	FindShortestPath(maze, maze.Begin(), maze.End(), &cells, &visitedCells)
+	if len(cells) != len(path) {
+		t.Errorf("expected path of %d cells, got %d", len(cells), len(path))
+	}
+	if again := Pack(maze).Unpack(); again.String() != maze.String() {
+		t.Error("expected same maze after pack and unpack")
+	}
+
+	visited.Clear()
+	packed = NewPackedMaze(w, h, point{0, 0}, point{w - 1, h - 1}, nil)
+	if _, found := FindPackedShortestPath(packed, packed.Begin(), packed.End(), visited); found {
+		t.Error("found path through walls")
+	}
+}
+
+func TestPackRejects(t *testing.T) {
+	wrapped, _ := NewMaze(4, 4, point{0, 0}, point{3, 3}, Wrapped(WrapCylinder, nil))
+	masked, _ := NewMaze(4, 4, point{0, 0}, point{3, 3}, nil)
+	masked.cells[1][1].masked = true
+	hex, _ := NewMaze(4, 4, point{0, 0}, point{3, 3}, Hexagonal(nil))
+	for expected, maze := range map[string]*Maze{
+		"wrapped mazes can't be packed":   wrapped,
+		"masked mazes can't be packed":    masked,
+		"only square mazes can be packed": hex,
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != expected {
+					t.Errorf("expected panic %q, got %v", expected, r)
+				}
+			}()
+			Pack(maze)
+		}()
+	}
+}
+
+func TestPackedDFSMemory(t *testing.T) {
+	// maze takes 2 bits per cell, generator 3 bits more
+	w, h := 1000, 1000
+	limit := uint64(w*h*5/8 + 64<<10)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	NewPackedMaze(w, h, point{0, 0}, point{w - 1, h - 1}, PackedDFS(1))
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > limit {
+		t.Errorf("expected at most %d bytes allocated, got %d", limit, n)
+	}
+}
+
+const benchSize = 500
+
+func BenchmarkDFS(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewMaze(benchSize, benchSize, point{0, 0}, point{benchSize - 1, benchSize - 1}, DFS(NewStack(), 1))
+	}
+}
+
+func BenchmarkPackedDFS(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewPackedMaze(benchSize, benchSize, point{0, 0}, point{benchSize - 1, benchSize - 1}, PackedDFS(1))
+	}
+}
+
+func BenchmarkBFS(b *testing.B) {
+	maze, _ := NewMaze(benchSize, benchSize, point{0, 0}, point{benchSize - 1, benchSize - 1}, DFS(NewStack(), 1))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		maze.ResetVisitedCells()
+		path := make([]*cell, 0, benchSize)
+		visited := make([]*cell, 0, benchSize)
+		FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited)
+	}
+}
+
+func BenchmarkPackedBFS(b *testing.B) {
+	maze := NewPackedMaze(benchSize, benchSize, point{0, 0}, point{benchSize - 1, benchSize - 1}, PackedDFS(1))
+	visited := NewBitset(maze.Len())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		visited.Clear()
+		FindPackedShortestPath(maze, maze.Begin(), maze.End(), visited)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+)
+
+// PathStream calls fn for every path in order, it stops and returns first
+// error returned by fn. Path is reused by stream, it's valid only until fn
+// returns
+type PathStream func(fn func(path []*cell) error) error
+
+// errPathLimit stops stream after limit paths
+var errPathLimit = errors.New("path limit reached")
+
+// SimplePaths streams every path from start to end which doesn't visit any
+// cell twice, at most limit of them, 0 is no limit. Paths are from end to
+// start like paths of solvers, they are found by DFS walking doors
+// backwards from end. Cells which can't be reached from start are skipped
+func SimplePaths(m *Maze, start, end *cell, limit int) PathStream {
+	return func(fn func(path []*cell) error) error {
+		reached, _ := m.reach([]*cell{start}, ^uint(0))
+		if _, ok := reached[end]; !ok {
+			return nil
+		}
+		path := []*cell{end}
+		on := map[*cell]bool{end: true}
+		found := 0
+		var walk func(c *cell) error
+		walk = func(c *cell) error {
+			if c == start {
+				found++
+				if err := fn(path); err != nil {
+					return err
+				}
+				if limit > 0 && found >= limit {
+					return errPathLimit
+				}
+				return nil
+			}
+			prev := m.AdjacentCells(c, func(n *cell) bool {
+				_, ok := reached[n]
+				return ok && !on[n] && isConnected(n, c)
+			})
+			for _, n := range prev {
+				path, on[n] = append(path, n), true
+				err := walk(n)
+				path, on[n] = path[:len(path)-1], false
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err := walk(end); err != errPathLimit {
+			return err
+		}
+		return nil
+	}
+}
+
+// maxPathMemo is the most bytes of memo kept by block of CountPaths
+var maxPathMemo = 32 << 20
+
+// CountPaths returns number of simple paths from start to end, counting
+// stops at limit, 0 is no limit, and exact is false then. Maze is split
+// to blocks which are joined by single cells, path passes blocks between
+// start and end in order, so count is product of counts inside blocks.
+// Counts inside block are memoized by cell and cells used so far, there
+// may be exponentially many of them. Memo is bounded, when it's full
+// the rest is counted path by path, so exact counts are feasible only
+// for blocks of few dozen cells and big mazes need limit
+func CountPaths(m *Maze, start, end *cell, limit int) (n int, exact bool) {
+	capped := func(n int) int {
+		if limit > 0 && n > limit {
+			return limit
+		}
+		return n
+	}
+	if start == end {
+		return 1, true
+	}
+	chain := m.blockChain(start, end)
+	if chain == nil {
+		return 0, true
+	}
+	total := 1
+	for i := 0; i+2 < len(chain); i += 2 {
+		from, to := chain[i].(*cell), chain[i+2].(*cell)
+		n := m.countInBlock(chain[i+1].(map[*cell]int), from, to, limit)
+		// product never exceeds limit, it's capped at every step
+		if limit > 0 && n > 0 && total > limit/n {
+			return limit, false
+		}
+		total = capped(total * n)
+		if total == 0 {
+			return 0, true
+		}
+	}
+	return total, limit <= 0 || total < limit
+}
+
+// countInBlock counts simple paths from c to end inside block, block maps
+// its cells to bit index
+func (m *Maze) countInBlock(block map[*cell]int, c, end *cell, limit int) int {
+	memo := map[string]int{}
+	memoSize := 0
+	used := make([]byte, (len(block)+7)/8)
+	set := func(c *cell, on bool) {
+		i := block[c]
+		if on {
+			used[i/8] |= 1 << uint(i%8)
+		} else {
+			used[i/8] &^= 1 << uint(i%8)
+		}
+	}
+	var count func(c *cell) int
+	count = func(c *cell) int {
+		if c == end {
+			return 1
+		}
+		// used has the same length for all cells of block
+		key := string(used) + strconv.Itoa(block[c])
+		if n, ok := memo[key]; ok {
+			return n
+		}
+		n := 0
+		for _, next := range m.AdjacentCells(c, func(n *cell) bool {
+			i, ok := block[n]
+			return ok && used[i/8]&(1<<uint(i%8)) == 0 && isConnected(c, n)
+		}) {
+			set(next, true)
+			n += count(next)
+			set(next, false)
+			if limit > 0 && n >= limit {
+				n = limit
+				break
+			}
+		}
+		// key and rough overhead of map entry and string
+		if memoSize+len(key)+64 <= maxPathMemo {
+			memo[key] = n
+			memoSize += len(key) + 64
+		}
+		return n
+	}
+	set(c, true)
+	return count(c)
+}
+
+// blockChain returns start, block, cut cell, block, ... end, blocks of
+// cells which path from start to end has to pass in order. Blocks are
+// biconnected components of maze with doors as edges in both directions.
+// Nil if end can't be reached
+func (m *Maze) blockChain(start, end *cell) []interface{} {
+	sides := func(c *cell) []*cell {
+		return m.AdjacentCells(c, func(n *cell) bool { return isConnected(c, n) || isConnected(n, c) })
+	}
+	// Tarjan's biconnected components of part of maze around start
+	order, low := map[*cell]int{}, map[*cell]int{}
+	blocks := []map[*cell]int{}
+	edges := [][2]*cell{}
+	var dfs func(c, parent *cell)
+	dfs = func(c, parent *cell) {
+		order[c] = len(order)
+		low[c] = order[c]
+		for _, n := range sides(c) {
+			if n == parent {
+				continue
+			}
+			if _, ok := order[n]; !ok {
+				edges = append(edges, [2]*cell{c, n})
+				dfs(n, c)
+				if low[n] < low[c] {
+					low[c] = low[n]
+				}
+				if low[n] >= order[c] {
+					// c separates block of edges above from rest
+					block := map[*cell]int{}
+					for {
+						e := edges[len(edges)-1]
+						edges = edges[:len(edges)-1]
+						for _, v := range e {
+							if _, ok := block[v]; !ok {
+								block[v] = len(block)
+							}
+						}
+						if e == [2]*cell{c, n} {
+							break
+						}
+					}
+					blocks = append(blocks, block)
+				}
+			} else if order[n] < order[c] {
+				edges = append(edges, [2]*cell{c, n})
+				if order[n] < low[c] {
+					low[c] = order[n]
+				}
+			}
+		}
+	}
+	dfs(start, nil)
+	if _, ok := order[end]; !ok {
+		return nil
+	}
+
+	// BFS in tree of cells and blocks they are in
+	in := map[*cell][]int{}
+	for i, b := range blocks {
+		for c := range b {
+			in[c] = append(in[c], i)
+		}
+	}
+	type node struct {
+		c     *cell
+		block int
+	}
+	from := map[node]node{{start, -1}: {}}
+	q := []node{{start, -1}}
+	for len(q) > 0 {
+		cur := q[0]
+		q = q[1:]
+		if cur.c == end {
+			chain := []interface{}{}
+			for n := cur; ; n = from[n] {
+				if n.c != nil {
+					chain = append(chain, n.c)
+				} else {
+					chain = append(chain, blocks[n.block])
+				}
+				if n == (node{start, -1}) {
+					break
+				}
+			}
+			for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+				chain[i], chain[j] = chain[j], chain[i]
+			}
+			return chain
+		}
+		next := []node{}
+		if cur.c != nil {
+			for _, b := range in[cur.c] {
+				next = append(next, node{nil, b})
+			}
+		} else {
+			for c := range blocks[cur.block] {
+				next = append(next, node{c, -1})
+			}
+		}
+		for _, n := range next {
+			if _, ok := from[n]; !ok {
+				from[n] = cur
+				q = append(q, n)
+			}
+		}
+	}
+	return nil
+}
+
+// KShortestPaths returns up to k shortest simple paths from start to end
+// by Yen's algorithm, shortest first. Each next path leaves some earlier
+// path at spur cell and continues by the shortest way which neither
+// revisits root of that path nor repeats known path. Paths are from end to
+// start
+func KShortestPaths(m *Maze, start, end *cell, k int) [][]*cell {
+	first := m.shortestAvoiding(start, end, nil, nil)
+	if first == nil || k < 1 {
+		return nil
+	}
+	// paths are kept from start to end here
+	found := [][]*cell{first}
+	candidates := [][]*cell{}
+	known := func(p []*cell) bool {
+		for _, paths := range [][][]*cell{found, candidates} {
+			for _, q := range paths {
+				if samePath(p, q) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for len(found) < k {
+		last := found[len(found)-1]
+		for i := 0; i < len(last)-1; i++ {
+			spur, root := last[i], last[:i+1]
+			cut := map[[2]*cell]bool{}
+			for _, p := range found {
+				if len(p) > i && samePath(p[:i+1], root) {
+					cut[[2]*cell{p[i], p[i+1]}] = true
+				}
+			}
+			blocked := map[*cell]bool{}
+			for _, c := range root[:i] {
+				blocked[c] = true
+			}
+			rest := m.shortestAvoiding(spur, end, blocked, cut)
+			if rest == nil {
+				continue
+			}
+			p := append(append([]*cell{}, root[:i]...), rest...)
+			if !known(p) {
+				candidates = append(candidates, p)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		// shortest candidate, earlier one wins ties
+		sort.SliceStable(candidates, func(i, j int) bool { return len(candidates[i]) < len(candidates[j]) })
+		found = append(found, candidates[0])
+		candidates = candidates[1:]
+	}
+	for _, p := range found {
+		for i, j := 0, len(p)-1; i < j; i, j = i+1, j-1 {
+			p[i], p[j] = p[j], p[i]
+		}
+	}
+	return found
+}
+
+// shortestAvoiding returns shortest path from start to end by BFS which
+// doesn't enter blocked cells or pass cut doors, nil if there is none.
+// Path is from start to end
+func (m *Maze) shortestAvoiding(start, end *cell, blocked map[*cell]bool, cut map[[2]*cell]bool) []*cell {
+	parent := map[*cell]*cell{start: nil}
+	q := []*cell{start}
+	for len(q) > 0 {
+		c := q[0]
+		q = q[1:]
+		if c == end {
+			path := []*cell{}
+			for ; c != nil; c = parent[c] {
+				path = append(path, c)
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+		for _, n := range m.AdjacentCells(c, func(n *cell) bool {
+			_, seen := parent[n]
+			return !seen && !blocked[n] && !cut[[2]*cell{c, n}] && isConnected(c, n)
+		}) {
+			parent[n] = c
+			q = append(q, n)
+		}
+	}
+	return nil
+}
+
+func samePath(p, q []*cell) bool {
+	if len(p) != len(q) {
+		return false
+	}
+	for i := range p {
+		if p[i] != q[i] {
+			return false
+		}
+	}
+	return true
+}
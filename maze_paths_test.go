@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+// openGrid returns w x h maze without inner walls
+func openGrid(w, h int) *Maze {
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, nil)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if x > 0 {
+				maze.RmWall(maze.cells[x-1][y], maze.cells[x][y])
+			}
+			if y > 0 {
+				maze.RmWall(maze.cells[x][y-1], maze.cells[x][y])
+			}
+		}
+	}
+	return maze
+}
+
+// braided returns DFS maze with extra doors making loops
+func braided(w, h int, seed int64) *Maze {
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), seed))
+	for i := 2; i < w-1; i += 3 {
+		for j := 1; j < h; j += 4 {
+			maze.RmWall(maze.cells[i][j], maze.cells[i+1][j])
+		}
+	}
+	maze.ResetVisitedCells()
+	return maze
+}
+
+func collect(t *testing.T, stream PathStream) [][]*cell {
+	paths := [][]*cell{}
+	err := stream(func(path []*cell) error {
+		paths = append(paths, append([]*cell{}, path...))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+func checkPath(t *testing.T, m *Maze, path []*cell) {
+	if path[0] != m.End() || path[len(path)-1] != m.Begin() {
+		t.Fatalf("path should go from end to begin")
+	}
+	seen := map[*cell]bool{}
+	for i, c := range path {
+		if seen[c] {
+			t.Fatalf("path visits %v twice", c.point)
+		}
+		seen[c] = true
+		if i > 0 && !isConnected(c, path[i-1]) {
+			t.Fatalf("path is broken at %v", c.point)
+		}
+	}
+}
+
+func TestSimplePaths(t *testing.T) {
+	// corner to corner paths of grid graphs
+	for n, expected := range map[int]int{2: 2, 3: 12, 4: 184, 5: 8512} {
+		maze := openGrid(n, n)
+		paths := collect(t, SimplePaths(maze, maze.Begin(), maze.End(), 0))
+		if len(paths) != expected {
+			t.Errorf("%dx%d: expected %d paths, got %d", n, n, expected, len(paths))
+		}
+		for _, p := range paths[:2] {
+			checkPath(t, maze, p)
+		}
+		if count, exact := CountPaths(maze, maze.Begin(), maze.End(), 0); count != expected || !exact {
+			t.Errorf("%dx%d: expected count %d, got %d", n, n, expected, count)
+		}
+	}
+
+	maze := openGrid(5, 5)
+	if paths := collect(t, SimplePaths(maze, maze.Begin(), maze.End(), 7)); len(paths) != 7 {
+		t.Errorf("expected 7 paths of limit, got %d", len(paths))
+	}
+	if count, exact := CountPaths(maze, maze.Begin(), maze.End(), 100); count != 100 || exact {
+		t.Errorf("expected count stopped at 100, got %d %v", count, exact)
+	}
+	stop := errors.New("stop")
+	n := 0
+	err := SimplePaths(maze, maze.Begin(), maze.End(), 0)(func([]*cell) error {
+		if n++; n == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || n != 3 {
+		t.Errorf("expected stream stopped by callback, got %v after %d", err, n)
+	}
+}
+
+func TestCountPaths(t *testing.T) {
+	perfect, _ := NewMaze(12, 12, point{0, 0}, point{11, 11}, DFS(NewStack(), 1))
+	if count, exact := CountPaths(perfect, perfect.Begin(), perfect.End(), 0); count != 1 || !exact {
+		t.Errorf("expected single path of perfect maze, got %d", count)
+	}
+
+	for seed := int64(1); seed < 6; seed++ {
+		maze := braided(10, 10, seed)
+		paths := collect(t, SimplePaths(maze, maze.Begin(), maze.End(), 0))
+		for _, p := range paths {
+			checkPath(t, maze, p)
+		}
+		if count, _ := CountPaths(maze, maze.Begin(), maze.End(), 0); count != len(paths) {
+			t.Errorf("seed %d: enumerated %d paths, counted %d", seed, len(paths), count)
+		}
+	}
+
+	// one way door closes one side of loop
+	maze, _ := NewMaze(2, 2, point{0, 0}, point{1, 1}, nil)
+	c := maze.cells
+	maze.RmWall(c[0][0], c[1][0])
+	maze.RmWall(c[1][0], c[1][1])
+	maze.AddOneWayDoor(c[0][1], c[0][0])
+	maze.AddOneWayDoor(c[0][1], c[1][1])
+	if count, _ := CountPaths(maze, maze.Begin(), maze.End(), 0); count != 1 {
+		t.Errorf("expected 1 path past one way doors, got %d", count)
+	}
+	if paths := collect(t, SimplePaths(maze, maze.Begin(), maze.End(), 0)); len(paths) != 1 {
+		t.Errorf("expected 1 enumerated path, got %d", len(paths))
+	}
+
+	// end cut off
+	maze, _ = NewMaze(3, 1, point{0, 0}, point{2, 0}, nil)
+	maze.RmWall(maze.cells[0][0], maze.cells[1][0])
+	if count, exact := CountPaths(maze, maze.Begin(), maze.End(), 0); count != 0 || !exact {
+		t.Errorf("expected no path, got %d", count)
+	}
+}
+
+func TestCountPathsMemoLimit(t *testing.T) {
+	defer func(size int) { maxPathMemo = size }(maxPathMemo)
+	// full memo, then counting path by path
+	for _, size := range []int{4 << 10, 0} {
+		maxPathMemo = size
+		maze := openGrid(5, 5)
+		if count, exact := CountPaths(maze, maze.Begin(), maze.End(), 0); count != 8512 || !exact {
+			t.Errorf("expected 8512 paths with memo of %d bytes, got %d", size, count)
+		}
+	}
+}
+
+func TestKShortestPaths(t *testing.T) {
+	// 20 shortest paths of 4x4 grid have 7 cells
+	maze := openGrid(4, 4)
+	paths := KShortestPaths(maze, maze.Begin(), maze.End(), 25)
+	if len(paths) != 25 {
+		t.Fatalf("expected 25 paths, got %d", len(paths))
+	}
+	for i, p := range paths {
+		checkPath(t, maze, p)
+		if i < 20 && len(p) != 7 || i >= 20 && len(p) != 9 {
+			t.Errorf("unexpected length %d of path %d", len(p), i)
+		}
+		for _, q := range paths[:i] {
+			if samePath(p, q) {
+				t.Errorf("path %d repeated", i)
+			}
+		}
+	}
+
+	// lengths match enumeration of all paths
+	for seed := int64(1); seed < 4; seed++ {
+		maze := braided(10, 10, seed)
+		all := collect(t, SimplePaths(maze, maze.Begin(), maze.End(), 0))
+		lengths := []int{}
+		for _, p := range all {
+			lengths = append(lengths, len(p))
+		}
+		sort.Ints(lengths)
+		paths := KShortestPaths(maze, maze.Begin(), maze.End(), len(all)+5)
+		if len(paths) != len(all) {
+			t.Errorf("seed %d: expected all %d paths, got %d", seed, len(all), len(paths))
+			continue
+		}
+		for i, p := range paths {
+			checkPath(t, maze, p)
+			if len(p) != lengths[i] {
+				t.Errorf("seed %d: expected path %d of %d cells, got %d", seed, i, lengths[i], len(p))
+			}
+		}
+	}
+}
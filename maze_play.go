@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Game is maze played by walking from begin to end
+type Game struct {
+	m     *Maze
+	pos   *cell
+	trail []*cell
+	hint  *cell
+	hints int
+	start time.Time
+}
+
+// NewGame returns game with player standing at begin of maze
+func NewGame(m *Maze) *Game {
+	return &Game{m: m, pos: m.Begin(), trail: []*cell{m.Begin()}, start: time.Now()}
+}
+
+// Move steps player through door in direction dx, dy on the same level,
+// it returns false if there is wall
+func (g *Game) Move(dx, dy int) bool {
+	l, layered := g.m.topology.(layeredGrid)
+	return g.step(func(c *cell) bool {
+		// stairs are taken with Climb
+		if layered && c.y/l.lh != g.pos.y/l.lh {
+			return false
+		}
+		x, y := g.m.direction(g.pos, c)
+		return x == dx && y == dy
+	})
+}
+
+// Climb takes stairs dz levels up (1) or down (-1) on Layered maze, it
+// returns false if there are no stairs
+func (g *Game) Climb(dz int) bool {
+	l, ok := g.m.topology.(layeredGrid)
+	if !ok {
+		return false
+	}
+	return g.step(func(c *cell) bool {
+		return c.x == g.pos.x && c.y == g.pos.y+dz*l.lh
+	})
+}
+
+// step moves player to first connected cell accepted by filter
+func (g *Game) step(filter func(*cell) bool) bool {
+	next := g.m.AdjacentCells(g.pos, func(c *cell) bool {
+		return isConnected(g.pos, c) && filter(c)
+	})
+	if len(next) == 0 {
+		return false
+	}
+	g.pos = next[0]
+	g.trail = append(g.trail, g.pos)
+	g.hint = nil
+	return true
+}
+
+// Moves returns number of steps made
+func (g *Game) Moves() int {
+	return len(g.trail) - 1
+}
+
+// Won returns if player reached end
+func (g *Game) Won() bool {
+	return g.pos == g.m.End()
+}
+
+// shortest returns shortest path from c to end, from end to c
+func (g *Game) shortest(c *cell) []*cell {
+	path := make([]*cell, 0, g.m.w)
+	visited := make([]*cell, 0, g.m.w)
+	g.m.ResetVisitedCells()
+	FindShortestPath(g.m, c, g.m.End(), &path, &visited)
+	g.m.ResetVisitedCells()
+	return path
+}
+
+// Hint returns next cell on shortest way to end, nil if there is none,
+// it's marked on maze until next move
+func (g *Game) Hint() *cell {
+	path := g.shortest(g.pos)
+	if len(path) < 2 {
+		return nil
+	}
+	g.hint = path[len(path)-2]
+	g.hints++
+	return g.hint
+}
+
+// Render returns maze text with trail of player as dots, player as @ and
+// hinted cell as *
+func (g *Game) Render() string {
+	lines := strings.Split(g.m.String(), "\n")
+	put := func(c *cell, mark byte) {
+		line := []byte(lines[2*c.y+1])
+		line[4*c.x+2] = mark
+		lines[2*c.y+1] = string(line)
+	}
+	for _, c := range g.trail {
+		if c != g.m.Begin() && c != g.m.End() {
+			put(c, '.')
+		}
+	}
+	if g.hint != nil {
+		put(g.hint, '*')
+	}
+	put(g.pos, '@')
+	return strings.Join(lines, "\n")
+}
+
+// Summary returns moves and time of player compared to shortest path
+func (g *Game) Summary() string {
+	best := len(g.shortest(g.m.Begin())) - 1
+	s := fmt.Sprintf("moves %d, time %v, hints %d", g.Moves(),
+		time.Since(g.start).Round(time.Second), g.hints)
+	if best < 0 {
+		return s + ", end is not reachable"
+	}
+	if g.Won() {
+		s += fmt.Sprintf(", shortest path %d, %d extra moves", best, g.Moves()-best)
+	}
+	return s
+}
+
+// Play runs game reading keys from in and drawing maze to out until player
+// reaches end or quits with q. Arrows and WASD move, < and > take stairs
+// up and down on Layered maze, h shows hint
+func Play(m *Maze, in io.Reader, out io.Writer) error {
+	g := NewGame(m)
+	r := bufio.NewReader(in)
+	// raw terminal needs carriage returns
+	show := func(s string) error {
+		_, err := io.WriteString(out, strings.Replace(s, "\n", "\r\n", -1))
+		return err
+	}
+	keys := "arrows/WASD move"
+	if _, ok := m.topology.(layeredGrid); ok {
+		keys += ", </> stairs"
+	}
+	for !g.Won() {
+		status := fmt.Sprintf("moves %d  %s, h hint, q quit\n", g.Moves(), keys)
+		if err := show("\x1b[H\x1b[2J" + g.Render() + status); err != nil {
+			return err
+		}
+		key, err := readKey(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch key {
+		case keyUp:
+			g.Move(0, -1)
+		case keyDown:
+			g.Move(0, 1)
+		case keyLeft:
+			g.Move(-1, 0)
+		case keyRight:
+			g.Move(1, 0)
+		case keyStairsUp:
+			g.Climb(1)
+		case keyStairsDown:
+			g.Climb(-1)
+		case keyHint:
+			g.Hint()
+		case keyQuit:
+			return show(g.Summary() + "\n")
+		}
+	}
+	return show("\x1b[H\x1b[2J" + g.Render() + g.Summary() + "\n")
+}
+
+type keyPress int
+
+const (
+	keyOther keyPress = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyStairsUp
+	keyStairsDown
+	keyHint
+	keyQuit
+)
+
+// readKey reads next key press, arrows come as escape sequences ESC [ A..D.
+// Lone ESC is key of its own, byte after it is left for next key
+func readKey(r *bufio.Reader) (keyPress, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return keyOther, err
+	}
+	if b == 0x1b {
+		if b, err = r.ReadByte(); err == io.EOF {
+			return keyOther, nil
+		} else if err != nil {
+			return keyOther, err
+		}
+		if b != '[' {
+			// can't fail right after ReadByte
+			_ = r.UnreadByte()
+			return keyOther, nil
+		}
+		if b, err = r.ReadByte(); err != nil {
+			return keyOther, err
+		}
+		switch b {
+		case 'A':
+			return keyUp, nil
+		case 'B':
+			return keyDown, nil
+		case 'C':
+			return keyRight, nil
+		case 'D':
+			return keyLeft, nil
+		}
+		return keyOther, nil
+	}
+	switch b {
+	case 'w', 'W':
+		return keyUp, nil
+	case 's', 'S':
+		return keyDown, nil
+	case 'a', 'A':
+		return keyLeft, nil
+	case 'd', 'D':
+		return keyRight, nil
+	case '<':
+		return keyStairsUp, nil
+	case '>':
+		return keyStairsDown, nil
+	case 'h', 'H':
+		return keyHint, nil
+	case 'q', 'Q', 3: // ctrl-c
+		return keyQuit, nil
+	}
+	return keyOther, nil
+}
+
+// PlayTerminal plays maze in terminal, it switches terminal to raw mode
+// with stty while game runs
+func PlayTerminal(m *Maze) error {
+	if err := stty("raw", "-echo"); err != nil {
+		return err
+	}
+	defer stty("-raw", "echo")
+	return Play(m, os.Stdin, os.Stdout)
+}
+
+func stty(args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
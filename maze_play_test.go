@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestGame(t *testing.T) {
+	maze, _ := NewMaze(3, 2, point{0, 0}, point{2, 1}, nil)
+	c := maze.cells
+	maze.RmWall(c[0][0], c[1][0])
+	maze.RmWall(c[1][0], c[2][0])
+	maze.RmWall(c[2][0], c[2][1])
+	maze.RmWall(c[0][0], c[0][1])
+	g := NewGame(maze)
+
+	if g.Move(0, -1) || g.Move(-1, 0) {
+		t.Error("moved through wall")
+	}
+	if !g.Move(0, 1) || !g.Move(0, -1) {
+		t.Error("move failed")
+	}
+	if hint := g.Hint(); hint != c[1][0] {
+		t.Errorf("expected hint to %v", c[1][0].point)
+	}
+	expected := "" +
+		"+---+---+---+\n" +
+		"| @   *     |\n" +
+		"+   +---+   +\n" +
+		"| . |   | E |\n" +
+		"+---+---+---+\n"
+	if s := g.Render(); s != expected {
+		t.Errorf("unexpected render\n%s", s)
+	}
+
+	g.Move(1, 0)
+	g.Move(1, 0)
+	g.Move(0, 1)
+	if !g.Won() || g.Moves() != 5 {
+		t.Errorf("expected win in 5 moves, got %d", g.Moves())
+	}
+	if s := g.Summary(); !strings.Contains(s, "shortest path 3, 2 extra moves") {
+		t.Errorf("unexpected summary %q", s)
+	}
+}
+
+func TestGameStairs(t *testing.T) {
+	// levels of two rows, stairs and door lead down from top left cell
+	maze, _ := NewMaze(1, 4, point{0, 0}, point{0, 3}, Layered(2, nil))
+	c := maze.cells
+	maze.RmWall(c[0][0], c[0][1])
+	maze.RmWall(c[0][0], c[0][2])
+	maze.RmWall(c[0][2], c[0][3])
+	g := NewGame(maze)
+
+	if !g.Move(0, 1) || g.pos != c[0][1] || !g.Move(0, -1) {
+		t.Error("expected door to cell on same level")
+	}
+	if g.Climb(-1) {
+		t.Error("climbed below first level")
+	}
+	if !g.Climb(1) || g.pos != c[0][2] {
+		t.Errorf("expected stairs to %v", c[0][2].point)
+	}
+	if g.Move(0, -1) {
+		t.Error("moved to other level")
+	}
+	if !g.Move(0, 1) || !g.Won() {
+		t.Error("expected win")
+	}
+	flat, _ := NewMaze(1, 2, point{0, 0}, point{0, 1}, nil)
+	flat.RmWall(flat.cells[0][0], flat.cells[0][1])
+	if NewGame(flat).Climb(1) {
+		t.Error("climbed on maze without levels")
+	}
+
+	out := &bytes.Buffer{}
+	if err := Play(maze, strings.NewReader("<s"), out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "</> stairs") || !strings.Contains(out.String(), "moves 2, time 0s") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestPlay(t *testing.T) {
+	maze, _ := NewMaze(3, 1, point{0, 0}, point{2, 0}, nil)
+	maze.RmWall(maze.cells[0][0], maze.cells[1][0])
+	maze.RmWall(maze.cells[1][0], maze.cells[2][0])
+
+	out := &bytes.Buffer{}
+	// wall up, arrow right, d right
+	if err := Play(maze, strings.NewReader("w\x1b[Cd"), out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "moves 2, time 0s, hints 0, shortest path 2, 0 extra moves\r\n") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	out.Reset()
+	if err := Play(maze, strings.NewReader("hq"), out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "| @   *   E |") || !strings.HasSuffix(out.String(), "hints 1\r\n") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	// key after lone escape is not lost
+	r := bufio.NewReader(strings.NewReader("\x1bq\x1b"))
+	for _, expected := range []keyPress{keyOther, keyQuit, keyOther} {
+		if key, err := readKey(r); key != expected || err != nil {
+			t.Errorf("expected key %d, got %d %v", expected, key, err)
+		}
+	}
+	if _, err := readKey(r); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
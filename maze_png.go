@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// apng frame dispose and blend operations
+const (
+	apngDisposeNone = 0
+	apngBlendSource = 0
+	apngBlendOver   = 1
+)
+
+// EncodeAPNG writes frames as true color animated png, plays loops times,
+// 0 means forever. Every frame except first holds only changed area with
+// unchanged pixels transparent, blended over previous frame
+func EncodeAPNG(w io.Writer, frames FrameStream, loops int) error {
+	type apngFrame struct {
+		rect  image.Rectangle
+		delay time.Duration
+		data  []byte
+	}
+	var (
+		prev   *image.RGBA
+		chunks []apngFrame
+	)
+	err := frames(func(f Frame) error {
+		changed := changedRect(f.Canvas, prev, f.Rect)
+		// first frame is IDAT, its fcTL must cover whole image
+		if prev == nil {
+			changed = f.Canvas.Bounds()
+		}
+		if changed.Empty() {
+			if len(chunks) > 0 {
+				chunks[len(chunks)-1].delay += f.Delay
+			}
+			return nil
+		}
+		first := prev == nil
+		if first {
+			prev = image.NewRGBA(f.Canvas.Bounds())
+		}
+		data, err := apngFrameData(f.Canvas, prev, changed, first)
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, apngFrame{changed, f.Delay, data})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		return errors.New("apng: no frames")
+	}
+
+	bounds := prev.Bounds()
+	if _, err := w.Write(pngSignature); err != nil {
+		return err
+	}
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(bounds.Dx()))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(bounds.Dy()))
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // true color with alpha
+	if err := writePNGChunk(w, "IHDR", ihdr); err != nil {
+		return err
+	}
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(chunks)))
+	binary.BigEndian.PutUint32(actl[4:], uint32(loops))
+	if err := writePNGChunk(w, "acTL", actl); err != nil {
+		return err
+	}
+
+	seq := uint32(0)
+	for i, c := range chunks {
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], seq)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(c.rect.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(c.rect.Dy()))
+		binary.BigEndian.PutUint32(fctl[12:], uint32(c.rect.Min.X-bounds.Min.X))
+		binary.BigEndian.PutUint32(fctl[16:], uint32(c.rect.Min.Y-bounds.Min.Y))
+		// delay in ms
+		ms := c.delay / time.Millisecond
+		if ms > 1<<16-1 {
+			ms = 1<<16 - 1
+		}
+		binary.BigEndian.PutUint16(fctl[20:], uint16(ms))
+		binary.BigEndian.PutUint16(fctl[22:], 1000)
+		fctl[24] = apngDisposeNone
+		fctl[25] = apngBlendOver
+		if i == 0 {
+			fctl[25] = apngBlendSource
+		}
+		if err := writePNGChunk(w, "fcTL", fctl); err != nil {
+			return err
+		}
+		seq++
+
+		// first frame is default image for png decoders
+		if i == 0 {
+			if err := writePNGChunk(w, "IDAT", c.data); err != nil {
+				return err
+			}
+			continue
+		}
+		fdat := make([]byte, 4+len(c.data))
+		binary.BigEndian.PutUint32(fdat, seq)
+		copy(fdat[4:], c.data)
+		if err := writePNGChunk(w, "fdAT", fdat); err != nil {
+			return err
+		}
+		seq++
+	}
+
+	return writePNGChunk(w, "IEND", nil)
+}
+
+// apngFrameData returns compressed rgba scanlines of canvas inside r,
+// pixels same as in prev are transparent unless it's first frame.
+// prev is updated with canvas
+func apngFrameData(canvas, prev *image.RGBA, r image.Rectangle, first bool) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	line := make([]byte, 1+4*r.Dx())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		// filter type none
+		line[0] = 0
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := canvas.RGBAAt(x, y)
+			i := 1 + 4*(x-r.Min.X)
+			if !first && c == prev.RGBAAt(x, y) {
+				line[i], line[i+1], line[i+2], line[i+3] = 0, 0, 0, 0
+				continue
+			}
+			// frames are opaque, so premultiplied rgba is same as png rgba
+			line[i], line[i+1], line[i+2], line[i+3] = c.R, c.G, c.B, c.A
+			prev.SetRGBA(x, y, c)
+		}
+		if _, err := zw.Write(line); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writePNGChunk(w io.Writer, name string, data []byte) error {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	copy(header[4:], name)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+	for _, b := range [][]byte{header, data, footer} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePNGSequence writes every frame as whole png image numbered from
+// frame-000000.png to dir, along with frames.txt ffmpeg concat list
+// with frame durations, e.g.
+// ffmpeg -f concat -i frames.txt -pix_fmt yuv444p maze.mp4
+func WritePNGSequence(dir string, frames FrameStream) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	list, err := os.Create(filepath.Join(dir, "frames.txt"))
+	if err != nil {
+		return err
+	}
+
+	var (
+		prev *image.RGBA
+		last string
+		dur  time.Duration
+		n    int
+	)
+	// write previous file when its duration is known
+	flush := func() error {
+		if last == "" {
+			return nil
+		}
+		_, err := fmt.Fprintf(list, "file '%s'\nduration %f\n", last, dur.Seconds())
+		return err
+	}
+	err = frames(func(f Frame) error {
+		if prev != nil && changedRect(f.Canvas, prev, f.Rect).Empty() {
+			// same image shown longer
+			dur += f.Delay
+			return nil
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		if prev == nil {
+			prev = image.NewRGBA(f.Canvas.Bounds())
+		}
+		draw.Draw(prev, prev.Bounds(), f.Canvas, prev.Bounds().Min, draw.Src)
+
+		last = fmt.Sprintf("frame-%06d.png", n)
+		dur = f.Delay
+		n++
+		file, err := os.Create(filepath.Join(dir, last))
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(file, f.Canvas); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	})
+	if err == nil {
+		err = flush()
+	}
+	// concat demuxer ignores duration of last entry unless file is repeated
+	if err == nil && last != "" {
+		_, err = fmt.Fprintf(list, "file '%s'\n", last)
+	}
+	if err != nil {
+		list.Close()
+		return err
+	}
+	return list.Close()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeAPNG(t *testing.T) {
+	w, h := 12, 8
+	cw, ch, ww := 10, 10, 2
+	maze, genPath := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 1))
+	maze.ResetVisitedCells()
+
+	frames := PathFrames(maze, genPath, nil, white, yellow, red, black, cw, ch, ww, 5, 40*time.Millisecond)
+	var buf bytes.Buffer
+	if err := EncodeAPNG(&buf, frames, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// default image is plain maze
+	img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != w*cw || b.Dy() != h*ch {
+		t.Errorf("unexpected size %v", b)
+	}
+	if c := img.At(cw/2, ch/2); !sameColor(c, white) {
+		t.Errorf("expected white cell, got %v", c)
+	}
+
+	// walk chunks, count frames
+	data := buf.Bytes()[len(pngSignature):]
+	numFrames, fctl := uint32(0), 0
+	for len(data) > 0 {
+		n := binary.BigEndian.Uint32(data)
+		name := string(data[4:8])
+		switch name {
+		case "acTL":
+			numFrames = binary.BigEndian.Uint32(data[8:])
+		case "fcTL":
+			fctl++
+		}
+		data = data[12+n:]
+	}
+	if numFrames == 0 || int(numFrames) != fctl {
+		t.Errorf("expected acTL frames %d to match fcTL chunks %d", numFrames, fctl)
+	}
+	if max := 1 + (len(genPath)+4)/5; fctl > max {
+		t.Errorf("expected at most %d frames, got %d", max, fctl)
+	}
+}
+
+func TestEncodeAPNGFirstFrame(t *testing.T) {
+	canvas := image.NewRGBA(image.Rect(0, 0, 30, 20))
+	frames := func(fn func(Frame) error) error {
+		// first frame claims only part of canvas changed
+		if err := fn(Frame{canvas, image.Rect(10, 10, 20, 20), 0}); err != nil {
+			return err
+		}
+		canvas.Set(5, 5, red)
+		return fn(Frame{canvas, image.Rect(5, 5, 6, 6), 0})
+	}
+	var buf bytes.Buffer
+	if err := EncodeAPNG(&buf, frames, 0); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()[len(pngSignature):]
+	for string(data[4:8]) != "fcTL" {
+		data = data[12+binary.BigEndian.Uint32(data):]
+	}
+	fctl := data[8:]
+	w, h := binary.BigEndian.Uint32(fctl[4:]), binary.BigEndian.Uint32(fctl[8:])
+	x, y := binary.BigEndian.Uint32(fctl[12:]), binary.BigEndian.Uint32(fctl[16:])
+	if w != 30 || h != 20 || x != 0 || y != 0 {
+		t.Errorf("expected first frame 30x20 at 0,0, got %dx%d at %d,%d", w, h, x, y)
+	}
+	if _, err := png.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWritePNGSequence(t *testing.T) {
+	w, h := 5, 5
+	maze, genPath := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 1))
+	maze.ResetVisitedCells()
+
+	dir := t.TempDir()
+	frames := PathFrames(maze, genPath, nil, white, yellow, red, black, 8, 8, 2, 1, 100*time.Millisecond)
+	if err := WritePNGSequence(dir, frames); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "frame-*.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cells revisited by generator don't change image
+	if len(files) < 2 || len(files) > len(genPath)+1 {
+		t.Errorf("expected up to %d frames, got %d", len(genPath)+1, len(files))
+	}
+	list, err := os.ReadFile(filepath.Join(dir, "frames.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(list), "file 'frame-000000.png'\nduration 0.1") {
+		t.Errorf("unexpected concat list %q", list)
+	}
+}
+
+func sameColor(c1, c2 color.Color) bool {
+	r1, g1, b1, a1 := c1.RGBA()
+	r2, g2, b2, a2 := c2.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// colors of portal pairs, cycled if there are more portals
+var portalColors = []color.Color{
+	color.RGBA{255, 0, 255, 255},
+	color.RGBA{0, 204, 255, 255},
+	color.RGBA{255, 153, 0, 255},
+	color.RGBA{153, 51, 255, 255},
+	color.RGBA{0, 153, 102, 255},
+	color.RGBA{153, 102, 51, 255},
+}
+
+// AddPortal connects two cells anywhere in maze, solvers may step from
+// one to the other in both directions
+func (m *Maze) AddPortal(c1, c2 *cell) {
+	if c1 == c2 {
+		panic("portal should connect different cells")
+	}
+	if !c1.linked(c2) {
+		c1.links = append(c1.links, c2)
+	}
+	if !c2.linked(c1) {
+		c2.links = append(c2.links, c1)
+	}
+	m.portals = append(m.portals, [2]*cell{c1, c2})
+}
+
+// AddOneWayDoor replaces wall or door between adjacent cells with door
+// which can be passed only from cell from to cell to
+func (m *Maze) AddOneWayDoor(from, to *cell) {
+	if !m.isAdjacent(from, to) {
+		panic("cells not adjacent or same")
+	}
+	m.closeDoor(from, to)
+	from.links = append(from.links, to)
+	m.oneWay = append(m.oneWay, [2]*cell{from, to})
+}
+
+// closeDoor puts wall back between adjacent cells
+func (m *Maze) closeDoor(c1, c2 *cell) {
+	c1.unlink(c2)
+	c2.unlink(c1)
+	if _, ok := m.topology.(planar); !ok {
+		return
+	}
+	dx, dy := m.direction(c1, c2)
+	switch {
+	case dx < 0:
+		c1.left, c2.right = false, false
+	case dx > 0:
+		c1.right, c2.left = false, false
+	case dy < 0:
+		c1.up, c2.down = false, false
+	case dy > 0:
+		c1.down, c2.up = false, false
+	}
+}
+
+func (c *cell) unlink(c2 *cell) {
+	for i, l := range c.links {
+		if l == c2 {
+			c.links = append(c.links[:i], c.links[i+1:]...)
+			return
+		}
+	}
+}
+
+// direction returns unit step from c1 towards c2 along dominant axis,
+// across wrapped edges for square grids
+func (m *Maze) direction(c1, c2 *cell) (dx, dy int) {
+	dx, dy = c2.x-c1.x, c2.y-c1.y
+	if g, ok := m.topology.(squareGrid); ok {
+		// unwrap takes c1 - c2
+		ux, uy, _ := g.unwrap(m, -dx, -dy)
+		dx, dy = -ux, -uy
+	}
+	if abs(dx) >= abs(dy) {
+		dy = 0
+	} else {
+		dx = 0
+	}
+	if dx != 0 {
+		dx /= abs(dx)
+	}
+	if dy != 0 {
+		dy /= abs(dy)
+	}
+	return dx, dy
+}
+
+// mark returns text mark of cell, letter of portal pair, arrow of one
+// way door or color of key, empty if none
+func (m *Maze) mark(c *cell) string {
+	for i, p := range m.portals {
+		if p[0] == c || p[1] == c {
+			return string(rune('a' + i%26))
+		}
+	}
+	for _, d := range m.oneWay {
+		if d[0] != c {
+			continue
+		}
+		switch dx, dy := m.direction(d[0], d[1]); {
+		case dx < 0:
+			return "<"
+		case dx > 0:
+			return ">"
+		case dy < 0:
+			return "^"
+		default:
+			return "v"
+		}
+	}
+	for _, k := range m.keys {
+		if k.c == c {
+			return fmt.Sprint(k.color)
+		}
+	}
+	return ""
+}
+
+// drawMarks draws portal, one way door and key marks of cell c, portals
+// are squares colored same for both ends, one way doors are arrows pointing
+// to the cell door leads to. Cell rect is shifted by offset on img
+func (m *Maze) drawMarks(img draw.Image, c *cell, offset image.Point, border color.Color, cw, ch, ww int) {
+	rect := cellRect(c, cw, ch).Sub(offset)
+	for i, p := range m.portals {
+		if p[0] == c || p[1] == c {
+			draw.Draw(img, rect.Inset(cw/4), image.NewUniform(portalColors[i%len(portalColors)]), image.Point{}, draw.Src)
+		}
+	}
+	for _, d := range m.oneWay {
+		if d[0] == c {
+			dx, dy := m.direction(d[0], d[1])
+			fillTriangle(img, rect.Inset(ww+cw/8), point{dx, dy}, border)
+		}
+	}
+	m.drawKeys(img, c, offset, cw, ch, ww)
+}
+
+// drawAllMarks draws marks of all cells with portals, one way doors, keys
+// or locks
+func (m *Maze) drawAllMarks(img draw.Image, border color.Color, cw, ch, ww int) {
+	for _, p := range m.portals {
+		m.drawMarks(img, p[0], image.Point{}, border, cw, ch, ww)
+		m.drawMarks(img, p[1], image.Point{}, border, cw, ch, ww)
+	}
+	for _, d := range m.oneWay {
+		m.drawMarks(img, d[0], image.Point{}, border, cw, ch, ww)
+	}
+	for _, k := range m.keys {
+		m.drawKeys(img, k.c, image.Point{}, cw, ch, ww)
+	}
+	for _, l := range m.locks {
+		m.drawKeys(img, l.c1, image.Point{}, cw, ch, ww)
+		m.drawKeys(img, l.c2, image.Point{}, cw, ch, ww)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+type solver func(m *Maze, start, end *cell, path, visited *[]*cell) bool
+
+var solvers = map[string]solver{
+	"dfs":   FindPath,
+	"bfs":   FindShortestPath,
+	"astar": FindShortestPathAStar,
+}
+
+func solve(m *Maze, s solver, start, end *cell) ([]*cell, bool) {
+	path := make([]*cell, 0, m.w)
+	visited := make([]*cell, 0, m.w)
+	found := s(m, start, end, &path, &visited)
+	m.ResetVisitedCells()
+	return path, found
+}
+
+func TestOneWayDoor(t *testing.T) {
+	maze, _ := NewMaze(4, 1, point{0, 0}, point{3, 0}, nil)
+	c := maze.cells
+	maze.RmWall(c[0][0], c[1][0])
+	maze.RmWall(c[1][0], c[2][0])
+	maze.RmWall(c[2][0], c[3][0])
+	maze.AddOneWayDoor(c[2][0], c[1][0])
+
+	if c[1][0].right || c[2][0].left {
+		t.Error("expected wall replaced by one way door")
+	}
+	for name, s := range solvers {
+		if _, found := solve(maze, s, maze.Begin(), maze.End()); found {
+			t.Errorf("%s: passed one way door in wrong direction", name)
+		}
+		if path, found := solve(maze, s, maze.End(), maze.Begin()); !found || len(path) != 4 {
+			t.Errorf("%s: expected path through one way door, got %d cells", name, len(path))
+		}
+	}
+	if s := maze.String(); !strings.Contains(s, "| S     | <   E |") {
+		t.Errorf("unexpected maze\n%s", s)
+	}
+}
+
+func TestPortal(t *testing.T) {
+	w, h := 12, 12
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 1))
+	maze.ResetVisitedCells()
+	// portal next to start leads next to end
+	a, b := maze.AdjacentCells(maze.Begin(), func(c *cell) bool {
+		return isConnected(maze.Begin(), c)
+	})[0], maze.AdjacentCells(maze.End(), func(c *cell) bool {
+		return isConnected(c, maze.End())
+	})[0]
+	maze.AddPortal(a, b)
+
+	for name, s := range solvers {
+		path, found := solve(maze, s, maze.Begin(), maze.End())
+		if !found {
+			t.Errorf("%s: path not found", name)
+		}
+		if name != "dfs" && len(path) != 4 {
+			t.Errorf("%s: expected path through portal, got %d cells", name, len(path))
+		}
+	}
+
+	img := Draw(maze, white, black, 20, 20, 2)
+	for _, c := range []*cell{a, b} {
+		if col := img.At(c.x*20+10, c.y*20+10); !sameColor(col, portalColors[0]) {
+			t.Errorf("expected portal mark at %v, got %v", c.point, col)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+// exactRouteLimit is max number of checkpoints for which order is searched
+// exhaustively, bigger routes are planned by heuristic
+const exactRouteLimit = 12
+
+// FindRoute finds route from begin through all checkpoints to end, order of
+// checkpoints is picked to make route shortest. It's exact up to
+// exactRouteLimit checkpoints, above it nearest neighbour order improved by
+// reversing parts of route. Path is from end to start like other solvers and
+// visited are cells searched on every leg. Returns order of checkpoints
+func FindRoute(m *Maze, checkpoints []point, path, visited *[]*cell) ([]int, bool) {
+	// stops are begin, checkpoints and end
+	stops := make([]*cell, 0, len(checkpoints)+2)
+	stops = append(stops, m.Begin())
+	for _, p := range checkpoints {
+		if !m.inside(p) {
+			panic("checkpoint should be inside maze")
+		}
+		stops = append(stops, m.cells[p.x][p.y])
+	}
+	stops = append(stops, m.End())
+
+	// pairwise distances, doors may be one way so both directions
+	dist := make([][]int, len(stops))
+	for i, s := range stops {
+		reached, _ := m.reach([]*cell{s}, 0)
+		dist[i] = make([]int, len(stops))
+		for j, t := range stops {
+			d, ok := reached[t]
+			if !ok {
+				return nil, false
+			}
+			dist[i][j] = d
+		}
+	}
+
+	var order []int
+	if len(checkpoints) <= exactRouteLimit {
+		order = exactRoute(dist)
+	} else {
+		order = heuristicRoute(dist)
+	}
+
+	// join legs, each leg is from its end to start
+	legs := make([][]*cell, 0, len(order)+1)
+	from := 0
+	for _, i := range append(order, len(stops)-2) {
+		to := i + 1
+		leg := make([]*cell, 0, dist[from][to]+1)
+		if stops[from] == stops[to] {
+			leg = append(leg, stops[to])
+		} else {
+			FindShortestPath(m, stops[from], stops[to], &leg, visited)
+			m.ResetVisitedCells()
+		}
+		legs = append(legs, leg)
+		from = to
+	}
+	for i := len(legs) - 1; i >= 0; i-- {
+		if i < len(legs)-1 {
+			// first cell of leg is last of next one
+			legs[i] = legs[i][1:]
+		}
+		*path = append(*path, legs[i]...)
+	}
+	return order, true
+}
+
+// routeLen returns length of route from first stop through order of
+// checkpoints to last stop, checkpoint i is stop i+1
+func routeLen(dist [][]int, order []int) int {
+	n, prev := 0, 0
+	for _, i := range order {
+		n += dist[prev][i+1]
+		prev = i + 1
+	}
+	return n + dist[prev][len(dist)-1]
+}
+
+// exactRoute returns shortest order of checkpoints, Held-Karp dynamic
+// programming over subsets of visited checkpoints
+func exactRoute(dist [][]int) []int {
+	n := len(dist) - 2
+	if n == 0 {
+		return []int{}
+	}
+	// best[set][i] is shortest route from begin through set ending at i
+	best := make([][]int, 1<<uint(n))
+	prev := make([][]int, 1<<uint(n))
+	for set := range best {
+		best[set] = make([]int, n)
+		prev[set] = make([]int, n)
+		for i := range best[set] {
+			best[set][i] = -1
+		}
+	}
+	for i := 0; i < n; i++ {
+		best[1<<uint(i)][i] = dist[0][i+1]
+		prev[1<<uint(i)][i] = -1
+	}
+	for set := 1; set < 1<<uint(n); set++ {
+		for i := 0; i < n; i++ {
+			if best[set][i] < 0 {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				if set&(1<<uint(j)) != 0 {
+					continue
+				}
+				next := set | 1<<uint(j)
+				if d := best[set][i] + dist[i+1][j+1]; best[next][j] < 0 || d < best[next][j] {
+					best[next][j] = d
+					prev[next][j] = i
+				}
+			}
+		}
+	}
+
+	all := 1<<uint(n) - 1
+	last := 0
+	for i := 1; i < n; i++ {
+		if best[all][i]+dist[i+1][n+1] < best[all][last]+dist[last+1][n+1] {
+			last = i
+		}
+	}
+	order := make([]int, n)
+	for set, i, k := all, last, n-1; i >= 0; k-- {
+		order[k] = i
+		set, i = set&^(1<<uint(i)), prev[set][i]
+	}
+	return order
+}
+
+// heuristicRoute returns order of checkpoints picking nearest one first,
+// then reversing parts of route while it gets shorter (2-opt)
+func heuristicRoute(dist [][]int) []int {
+	n := len(dist) - 2
+	order := make([]int, 0, n)
+	used := make([]bool, n)
+	prev := 0
+	for len(order) < n {
+		next := -1
+		for i := 0; i < n; i++ {
+			if !used[i] && (next < 0 || dist[prev][i+1] < dist[prev][next+1]) {
+				next = i
+			}
+		}
+		used[next] = true
+		order = append(order, next)
+		prev = next + 1
+	}
+
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < n-1; i++ {
+			for j := i + 1; j < n; j++ {
+				before := routeLen(dist, order)
+				reverse(order[i : j+1])
+				if routeLen(dist, order) < before {
+					improved = true
+				} else {
+					reverse(order[i : j+1])
+				}
+			}
+		}
+	}
+	return order
+}
+
+func reverse(order []int) {
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+// permutations calls fn for every order of 0..n-1
+func permutations(order []int, k int, fn func([]int)) {
+	if k == len(order) {
+		fn(order)
+		return
+	}
+	for i := k; i < len(order); i++ {
+		order[k], order[i] = order[i], order[k]
+		permutations(order, k+1, fn)
+		order[k], order[i] = order[i], order[k]
+	}
+}
+
+func checkRoute(t *testing.T, m *Maze, checkpoints []point, path []*cell) {
+	if path[0] != m.End() || path[len(path)-1] != m.Begin() {
+		t.Error("route should go from end to start")
+	}
+	on := map[point]bool{}
+	for i, c := range path {
+		on[c.point] = true
+		if i > 0 && !isConnected(c, path[i-1]) {
+			t.Errorf("route is broken at %v", c.point)
+		}
+	}
+	for _, p := range checkpoints {
+		if !on[p] {
+			t.Errorf("checkpoint %v not on route", p)
+		}
+	}
+}
+
+func TestFindRoute(t *testing.T) {
+	w, h := 15, 15
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, DFS(NewStack(), 1))
+	maze.ResetVisitedCells()
+	checkpoints := []point{{14, 0}, {3, 7}, {0, 14}, {7, 7}, {10, 2}, {0, 0}}
+
+	path := make([]*cell, 0, w)
+	visited := make([]*cell, 0, w)
+	order, found := FindRoute(maze, checkpoints, &path, &visited)
+	if !found {
+		t.Fatal("route not found")
+	}
+	checkRoute(t, maze, checkpoints, path)
+
+	// compare with all orders
+	stops := append([]point{maze.entry}, append(checkpoints, maze.exit)...)
+	dist := make([][]int, len(stops))
+	for i, s := range stops {
+		reached, _ := maze.reach([]*cell{maze.cells[s.x][s.y]}, 0)
+		for _, t := range stops {
+			dist[i] = append(dist[i], reached[maze.cells[t.x][t.y]])
+		}
+	}
+	best := -1
+	permutations([]int{0, 1, 2, 3, 4, 5}, 0, func(o []int) {
+		if l := routeLen(dist, o); best < 0 || l < best {
+			best = l
+		}
+	})
+	if l := routeLen(dist, order); l != best || len(path) != best+1 {
+		t.Errorf("expected route of %d steps, got %d and path of %d cells", best, l, len(path))
+	}
+
+	// all cells are checkpoints, heuristic is used
+	all := []point{}
+	for x := 0; x < w; x += 2 {
+		for y := 0; y < h; y += 2 {
+			all = append(all, point{x, y})
+		}
+	}
+	path, visited = path[:0], visited[:0]
+	if _, found := FindRoute(maze, all, &path, &visited); !found {
+		t.Fatal("route not found")
+	}
+	checkRoute(t, maze, all, path)
+
+	// route animates like single path
+	anim := AnimatePath(maze, visited, path, yellow, red, black, 10, 10, 2, 1)
+	if len(anim.Image) != len(visited)+len(path)+1 {
+		t.Errorf("expected %d frames, got %d", len(visited)+len(path)+1, len(anim.Image))
+	}
+}
+
+func TestFindRouteUnreachable(t *testing.T) {
+	maze, _ := NewMaze(3, 1, point{0, 0}, point{1, 0}, nil)
+	maze.RmWall(maze.cells[0][0], maze.cells[1][0])
+	path, visited := []*cell{}, []*cell{}
+	if _, found := FindRoute(maze, []point{{2, 0}}, &path, &visited); found {
+		t.Error("found route through walled cell")
+	}
+	if _, found := FindRoute(maze, nil, &path, &visited); !found || len(path) != 2 {
+		t.Errorf("expected route without checkpoints, got %d cells", len(path))
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// MazeStats are measures of single maze. Ratios are of cells which are
+// not masked, directions are shares of solution steps left, right, up and
+// down, solution is 0 if there is none
+type MazeStats struct {
+	Seed            int64
+	DeadEnds        float64
+	Corridor        float64
+	LongestCorridor int
+	Turns           float64
+	Horizontal      float64
+	Directions      [4]float64
+	Solution        int
+}
+
+// GeneratorStats are stats of mazes of code generated with consecutive
+// seeds
+type GeneratorStats struct {
+	Code  MazeCode
+	Mazes []MazeStats
+}
+
+// StatSummary is distribution of one metric over mazes of generator
+type StatSummary struct {
+	Metric                     string
+	Mean, StdDev               float64
+	Min, P10, Median, P90, Max float64
+}
+
+// statsMetrics are columns of CSV and panels of charts
+var statsMetrics = []struct {
+	name  string
+	value func(s MazeStats) float64
+}{
+	{"dead_ends", func(s MazeStats) float64 { return s.DeadEnds }},
+	{"corridor", func(s MazeStats) float64 { return s.Corridor }},
+	{"longest_corridor", func(s MazeStats) float64 { return float64(s.LongestCorridor) }},
+	{"turns", func(s MazeStats) float64 { return s.Turns }},
+	{"horizontal", func(s MazeStats) float64 { return s.Horizontal }},
+	{"left", func(s MazeStats) float64 { return s.Directions[0] }},
+	{"right", func(s MazeStats) float64 { return s.Directions[1] }},
+	{"up", func(s MazeStats) float64 { return s.Directions[2] }},
+	{"down", func(s MazeStats) float64 { return s.Directions[3] }},
+	{"solution", func(s MazeStats) float64 { return float64(s.Solution) }},
+}
+
+// Measure returns stats of maze. Corridors are passages between junctions,
+// dead ends, begin and end, turns are solution cells where path changes
+// direction
+func Measure(m *Maze) MazeStats {
+	s := MazeStats{}
+	cells, deadEnds, doors, horizontal := 0, 0, 0, 0
+	m.eachCell(func(c *cell) {
+		cells++
+		sides := m.AdjacentCells(c, func(n *cell) bool { return isConnected(c, n) || isConnected(n, c) })
+		if len(sides) == 1 {
+			deadEnds++
+		}
+		for _, n := range sides {
+			doors++
+			if _, dy := m.direction(c, n); dy == 0 {
+				horizontal++
+			}
+		}
+	})
+	if cells > 0 {
+		s.DeadEnds = float64(deadEnds) / float64(cells)
+	}
+	if doors > 0 {
+		s.Horizontal = float64(horizontal) / float64(doors)
+	}
+
+	edges := CompressedGraph(m).Edges
+	for _, e := range edges {
+		s.Corridor += float64(e.Weight)
+		if e.Weight > s.LongestCorridor {
+			s.LongestCorridor = e.Weight
+		}
+	}
+	if len(edges) > 0 {
+		s.Corridor /= float64(len(edges))
+	}
+
+	if m.Begin().masked || m.End().masked {
+		return s
+	}
+	path := make([]*cell, 0, m.w)
+	visited := make([]*cell, 0, m.w)
+	m.ResetVisitedCells()
+	found := false
+	if len(m.keys) > 0 {
+		found = FindKeyPath(m, m.Begin(), m.End(), &path, &[]KeyAction{})
+	} else {
+		found = FindShortestPath(m, m.Begin(), m.End(), &path, &visited)
+	}
+	m.ResetVisitedCells()
+	if !found {
+		return s
+	}
+	s.Solution = len(path)
+	turns, last := 0, point{}
+	// path is from end to start
+	for i := len(path) - 1; i > 0; i-- {
+		dx, dy := m.direction(path[i], path[i-1])
+		switch {
+		case dx < 0:
+			s.Directions[0]++
+		case dx > 0:
+			s.Directions[1]++
+		case dy < 0:
+			s.Directions[2]++
+		default:
+			s.Directions[3]++
+		}
+		if d := (point{dx, dy}); i < len(path)-1 && d != last {
+			turns++
+		}
+		last = point{dx, dy}
+	}
+	if steps := float64(len(path) - 1); steps > 0 {
+		for i := range s.Directions {
+			s.Directions[i] /= steps
+		}
+		s.Turns = float64(turns) / steps
+	}
+	return s
+}
+
+// Analyze generates n mazes of each code with seeds from seed of code on
+// and measures them. Mazes are generated concurrently
+func Analyze(codes []MazeCode, n int) ([]GeneratorStats, error) {
+	stats := make([]GeneratorStats, len(codes))
+	errs := make([]error, len(codes)*n)
+	for i, code := range codes {
+		if err := code.check(); err != nil {
+			return nil, err
+		}
+		stats[i] = GeneratorStats{code, make([]MazeStats, n)}
+	}
+	parallel(len(codes)*n, func(j int) {
+		c := codes[j/n]
+		c.Seed += int64(j % n)
+		m, _, err := c.Generate()
+		if err != nil {
+			errs[j] = err
+			return
+		}
+		stats[j/n].Mazes[j%n] = Measure(m)
+		stats[j/n].Mazes[j%n].Seed = c.Seed
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// Summary returns distribution of every metric over mazes
+func (g GeneratorStats) Summary() []StatSummary {
+	summary := []StatSummary{}
+	for _, metric := range statsMetrics {
+		values := make([]float64, len(g.Mazes))
+		for i, s := range g.Mazes {
+			values[i] = metric.value(s)
+		}
+		summary = append(summary, summarize(metric.name, values))
+	}
+	return summary
+}
+
+func summarize(metric string, values []float64) StatSummary {
+	s := StatSummary{Metric: metric}
+	if len(values) == 0 {
+		return s
+	}
+	sort.Float64s(values)
+	for _, v := range values {
+		s.Mean += v
+	}
+	s.Mean /= float64(len(values))
+	for _, v := range values {
+		s.StdDev += (v - s.Mean) * (v - s.Mean)
+	}
+	s.StdDev = math.Sqrt(s.StdDev / float64(len(values)))
+	at := func(q float64) float64 { return values[int(q*float64(len(values)-1)+0.5)] }
+	s.Min, s.P10, s.Median, s.P90, s.Max = values[0], at(0.1), at(0.5), at(0.9), values[len(values)-1]
+	return s
+}
+
+// name returns code of generator without seed
+func (g GeneratorStats) name() string {
+	c := g.Code
+	c.Seed = 0
+	code, err := Encode(c)
+	if err != nil {
+		return c.Generator
+	}
+	return strings.TrimSuffix(code, "-0")
+}
+
+// WriteStatsCSV writes row of stats per maze, first column is code which
+// regenerates the maze
+func WriteStatsCSV(w io.Writer, stats []GeneratorStats) error {
+	out := csv.NewWriter(w)
+	header := []string{"code"}
+	for _, metric := range statsMetrics {
+		header = append(header, metric.name)
+	}
+	out.Write(header)
+	for _, g := range stats {
+		for _, s := range g.Mazes {
+			c := g.Code
+			c.Seed = s.Seed
+			code, err := Encode(c)
+			if err != nil {
+				return err
+			}
+			row := []string{code}
+			for _, metric := range statsMetrics {
+				row = append(row, strconv.FormatFloat(metric.value(s), 'g', 6, 64))
+			}
+			out.Write(row)
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteStatsSummaryCSV writes row of distribution per generator and metric
+func WriteStatsSummaryCSV(w io.Writer, stats []GeneratorStats) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"generator", "metric", "mean", "stddev", "min", "p10", "median", "p90", "max"})
+	for _, g := range stats {
+		for _, s := range g.Summary() {
+			row := []string{g.name(), s.Metric}
+			for _, v := range []float64{s.Mean, s.StdDev, s.Min, s.P10, s.Median, s.P90, s.Max} {
+				row = append(row, strconv.FormatFloat(v, 'g', 6, 64))
+			}
+			out.Write(row)
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// statsColors tell generators apart on charts
+var statsColors = []color.RGBA{
+	{31, 119, 180, 255},
+	{255, 127, 14, 255},
+	{44, 160, 44, 255},
+	{214, 39, 40, 255},
+	{148, 103, 189, 255},
+	{140, 86, 75, 255},
+	{227, 119, 194, 255},
+	{127, 127, 127, 255},
+}
+
+const (
+	chartW, chartH = 240, 160
+	chartPad       = 20
+)
+
+// DrawStatsCharts returns chart per metric, each has bar of mean per
+// generator with line from 10th to 90th percentile. Legend of generator
+// colors is on top
+func DrawStatsCharts(stats []GeneratorStats) *image.RGBA {
+	cols := 3
+	rows := (len(statsMetrics) + cols - 1) / cols
+	legend := 16 * (len(stats) + 1)
+	img := image.NewRGBA(image.Rect(0, 0, cols*chartW, legend+rows*chartH))
+	draw.Draw(img, img.Bounds(), image.NewUniform(white), image.Point{}, draw.Src)
+	fill := func(r image.Rectangle, c color.Color) {
+		draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	gray := color.RGBA{160, 160, 160, 255}
+
+	for i, g := range stats {
+		y := 8 + 16*i
+		fill(image.Rect(chartPad, y, chartPad+10, y+10), statsColors[i%len(statsColors)])
+		drawText(img, bookText{chartPad + 16, float64(y + 10), 13, g.name()}, 1)
+	}
+
+	summaries := make([][]StatSummary, len(stats))
+	for i, g := range stats {
+		summaries[i] = g.Summary()
+	}
+	for k, metric := range statsMetrics {
+		panel := image.Rect(0, 0, chartW, chartH).Add(image.Pt(k%cols*chartW, legend+k/cols*chartH))
+		plot := image.Rect(panel.Min.X+chartPad, panel.Min.Y+chartPad, panel.Max.X-chartPad, panel.Max.Y-chartPad)
+		top := 0.0
+		for i := range stats {
+			top = math.Max(top, summaries[i][k].P90)
+		}
+		drawText(img, bookText{float64(plot.Min.X), float64(plot.Min.Y - 6), 13,
+			fmt.Sprintf("%s (max %.3g)", metric.name, top)}, 1)
+		fill(image.Rect(plot.Min.X, plot.Max.Y, plot.Max.X, plot.Max.Y+1), gray)
+		if top == 0 || len(stats) == 0 {
+			continue
+		}
+		bw := plot.Dx() / len(stats)
+		height := func(v float64) int { return plot.Max.Y - int(v/top*float64(plot.Dy())) }
+		for i := range stats {
+			s := summaries[i][k]
+			x0 := plot.Min.X + i*bw + bw/8
+			x1 := plot.Min.X + (i+1)*bw - bw/8
+			fill(image.Rect(x0, height(s.Mean), x1, plot.Max.Y), statsColors[i%len(statsColors)])
+			mid := (x0 + x1) / 2
+			fill(image.Rect(mid, height(s.P90), mid+1, height(s.P10)+1), color.Black)
+			fill(image.Rect(mid-3, height(s.P90), mid+4, height(s.P90)+1), color.Black)
+			fill(image.Rect(mid-3, height(s.P10), mid+4, height(s.P10)+1), color.Black)
+		}
+	}
+	return img
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestMeasure(t *testing.T) {
+	// corridor with side branch
+	// +---+---+---+
+	// | S         |
+	// +---+   +---+
+	//     | E |
+	//     +---+
+	maze, _ := NewMaze(3, 2, point{0, 0}, point{1, 1}, nil)
+	c := maze.cells
+	maze.RmWall(c[0][0], c[1][0])
+	maze.RmWall(c[1][0], c[2][0])
+	maze.RmWall(c[1][0], c[1][1])
+	c[0][1].masked = true
+	c[2][1].masked = true
+
+	s := Measure(maze)
+	if s.DeadEnds != 0.75 {
+		t.Errorf("expected 3 of 4 dead ends, got %v", s.DeadEnds)
+	}
+	// corridors of 1 step from junction
+	if s.Corridor != 1 || s.LongestCorridor != 1 {
+		t.Errorf("unexpected corridors %v %v", s.Corridor, s.LongestCorridor)
+	}
+	if s.Horizontal != 4.0/6 {
+		t.Errorf("expected 4 of 6 door sides horizontal, got %v", s.Horizontal)
+	}
+	if s.Solution != 3 || s.Directions != [4]float64{0, 0.5, 0, 0.5} || s.Turns != 0.5 {
+		t.Errorf("unexpected solution %v %v %v", s.Solution, s.Directions, s.Turns)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	codes := []MazeCode{
+		{Generator: "DFS", W: 10, H: 10, Exit: point{9, 9}, Seed: 1},
+		{Generator: "BTREE", W: 10, H: 10, Exit: point{9, 9}, Seed: 1},
+	}
+	n := 30
+	stats, err := Analyze(codes, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dfs, btree := stats[0].Summary(), stats[1].Summary()
+	// binary tree never goes up from top left corner, its top row is
+	// always open
+	up := 7
+	if statsMetrics[up].name != "up" || btree[up].Max != 0 || dfs[up].Mean == 0 {
+		t.Errorf("expected up bias of binary tree, got %v and %v", btree[up], dfs[up])
+	}
+	// DFS makes long corridors and few dead ends
+	if dfs[0].Mean >= btree[0].Mean || dfs[1].Mean <= btree[1].Mean {
+		t.Errorf("expected fewer dead ends and longer corridors of DFS, got %v %v", dfs[:2], btree[:2])
+	}
+	for i, s := range stats[1].Mazes {
+		if s.Seed != int64(1+i) {
+			t.Errorf("unexpected seed %d of maze %d", s.Seed, i)
+		}
+	}
+	if _, err := Analyze([]MazeCode{{Generator: "BFS", W: 2, H: 2}}, 1); err == nil {
+		t.Error("expected error of unknown generator")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := summarize("x", []float64{5, 1, 4, 2, 3})
+	if s.Mean != 3 || s.Min != 1 || s.Max != 5 || s.Median != 3 || s.StdDev*s.StdDev < 1.99 || s.StdDev*s.StdDev > 2.01 {
+		t.Errorf("unexpected summary %v", s)
+	}
+}
+
+func TestWriteStatsCSV(t *testing.T) {
+	codes := []MazeCode{{Generator: "BTREE", W: 6, H: 4, Exit: point{5, 3}, Seed: 10}}
+	stats, err := Analyze(codes, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteStatsCSV(buf, stats); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || len(rows[0]) != len(statsMetrics)+1 || rows[3][0] != "BTREE-6x4-c" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+	// code regenerates measured maze
+	m, _, err := Decode(rows[3][0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := Measure(m); s.Solution != stats[0].Mazes[2].Solution {
+		t.Errorf("expected solution %d, got %d", stats[0].Mazes[2].Solution, s.Solution)
+	}
+
+	buf.Reset()
+	if err := WriteStatsSummaryCSV(buf, stats); err != nil {
+		t.Fatal(err)
+	}
+	rows, _ = csv.NewReader(buf).ReadAll()
+	if len(rows) != len(statsMetrics)+1 || rows[1][0] != "BTREE-6x4" || rows[1][1] != "dead_ends" {
+		t.Errorf("unexpected summary rows %v", rows[:2])
+	}
+}
+
+func TestDrawStatsCharts(t *testing.T) {
+	codes := []MazeCode{
+		{Generator: "DFS", W: 8, H: 8, Exit: point{7, 7}},
+		{Generator: "BTREE", W: 8, H: 8, Exit: point{7, 7}},
+	}
+	stats, err := Analyze(codes, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := DrawStatsCharts(stats)
+	if b := img.Bounds(); b.Dx() != 3*chartW || b.Dy() != 48+4*chartH {
+		t.Errorf("unexpected size %v", b)
+	}
+	// bars of both generators are in first panel
+	found := map[int]bool{}
+	for y := 48; y < 48+chartH; y++ {
+		for x := 0; x < chartW; x++ {
+			for i := range codes {
+				if img.At(x, y) == statsColors[i] {
+					found[i] = true
+				}
+			}
+		}
+	}
+	if len(found) != 2 {
+		t.Errorf("expected bars of 2 generators, got %v", found)
+	}
+}
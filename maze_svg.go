@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+)
+
+// DrawSVG writes maze as svg image, cells are cw x ch and walls are ww wide,
+// cells of non square grids are cw wide. Masked cells are left blank
+func DrawSVG(w io.Writer, m *Maze, fill, border color.Color, cw, ch, ww int) error {
+	if _, ok := m.topology.(planar); !ok {
+		return drawGridSVG(w, m, fill, border, cw, ww)
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		m.w*cw, m.h*ch, m.w*cw, m.h*ch)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", svgColor(fill))
+	fmt.Fprintf(bw, `<path fill="none" stroke="%s" stroke-width="%d" stroke-linecap="square" d="`,
+		svgColor(border), ww)
+
+	present := func(x, y int) bool {
+		return x >= 0 && x < m.w && y >= 0 && y < m.h && !m.cells[x][y].masked
+	}
+	for y := 0; y <= m.h; y++ {
+		for x := 0; x <= m.w; x++ {
+			// wall above cell, tunnel mouth is open on one side only,
+			// and border is open where maze wraps around
+			if x < m.w && (present(x, y) || present(x, y-1)) &&
+				!(present(x, y) && present(x, y-1) && (m.cells[x][y].up || m.cells[x][y-1].down) ||
+					y == 0 && present(x, y) && m.cells[x][y].up ||
+					y == m.h && present(x, y-1) && m.cells[x][y-1].down) {
+				fmt.Fprintf(bw, "M%d %dh%d", x*cw, y*ch, cw)
+			}
+			// wall left of cell
+			if y < m.h && (present(x, y) || present(x-1, y)) &&
+				!(present(x, y) && present(x-1, y) && (m.cells[x][y].left || m.cells[x-1][y].right) ||
+					x == 0 && present(x, y) && m.cells[x][y].left ||
+					x == m.w && present(x-1, y) && m.cells[x-1][y].right) {
+				fmt.Fprintf(bw, "M%d %dv%d", x*cw, y*ch, ch)
+			}
+			// walls of passage over tunnel are solid, tunnel walls have
+			// gap under it
+			if present(x, y) && m.cells[x][y].tunnel {
+				i, j := cw/4, ch/4
+				x0, y0, x1, y1 := x*cw, y*ch, x*cw+cw, y*ch+ch
+				if m.cells[x][y].up {
+					fmt.Fprintf(bw, "M%d %dv%dM%d %dv%d", x0+i, y0, ch, x1-i, y0, ch)
+					fmt.Fprintf(bw, "M%d %dh%dM%d %dh%d", x0, y0, i, x1-i, y0, i)
+					fmt.Fprintf(bw, "M%d %dh%dM%d %dh%d", x0, y1, i, x1-i, y1, i)
+				} else {
+					fmt.Fprintf(bw, "M%d %dh%dM%d %dh%d", x0, y0+j, cw, x0, y1-j, cw)
+					fmt.Fprintf(bw, "M%d %dv%dM%d %dv%d", x0, y0, j, x0, y1-j, j)
+					fmt.Fprintf(bw, "M%d %dv%dM%d %dv%d", x1, y0, j, x1, y1-j, j)
+				}
+			}
+		}
+	}
+	fmt.Fprint(bw, `"/>`+"\n</svg>\n")
+
+	return bw.Flush()
+}
+
+// drawGridSVG writes maze of any topology with walls as polylines, image
+// is padded by wall width so outer walls are not clipped
+func drawGridSVG(w io.Writer, m *Maze, fill, border color.Color, size, ww int) error {
+	bw := bufio.NewWriter(w)
+	iw, ih := m.topology.ImageSize(m, float64(size))
+	pad := float64(ww)
+	iw, ih = math.Ceil(iw+2*pad), math.Ceil(ih+2*pad)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g">`+"\n",
+		iw, ih, iw, ih)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", svgColor(fill))
+	fmt.Fprintf(bw, `<path fill="none" stroke="%s" stroke-width="%d" stroke-linecap="round" stroke-linejoin="round" d="`,
+		svgColor(border), ww)
+	closedWalls(m, float64(size), func(line []vec) {
+		for i, v := range line {
+			cmd := "L"
+			if i == 0 {
+				cmd = "M"
+			}
+			fmt.Fprintf(bw, "%s%.2f %.2f", cmd, v.x+pad, v.y+pad)
+		}
+	})
+	fmt.Fprint(bw, `"/>`+"\n</svg>\n")
+
+	return bw.Flush()
+}
+
+func svgColor(c color.Color) string {
+	if c == nil {
+		return "none"
+	}
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
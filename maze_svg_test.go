@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDrawSVG(t *testing.T) {
+	maze, _ := NewMaze(2, 1, point{0, 0}, point{1, 0}, nil)
+	maze.RmWall(maze.cells[0][0], maze.cells[1][0])
+
+	var buf bytes.Buffer
+	if err := DrawSVG(&buf, maze, white, black, 10, 10, 2); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	if !strings.Contains(svg, `width="20" height="10"`) {
+		t.Errorf("unexpected size in %s", svg)
+	}
+	// outer walls only, door between cells is open
+	if !strings.Contains(svg, `d="M0 0h10M0 0v10M10 0h10M20 0v10M0 10h10M10 10h10"`) {
+		t.Errorf("unexpected walls in %s", svg)
+	}
+
+	maze.cells[1][0].masked = true
+	buf.Reset()
+	if err := DrawSVG(&buf, maze, white, black, 10, 10, 2); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `d="M0 0h10M0 0v10M10 0v10M0 10h10"`) {
+		t.Errorf("unexpected walls with masked cell in %s", buf.String())
+	}
+}
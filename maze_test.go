@@ -90,3 +90,81 @@ func TestMaze(t *testing.T) {
 	}
 
 }
+
+func TestFindShortestPathAStar(t *testing.T) {
+	w, h := 20, 20
+	generators := map[string]func(*Maze) (*Maze, []*cell){
+		"square": DFS(NewStack(), 1),
+		"torus":  Wrapped(WrapTorus, DFS(NewStack(), 2)),
+		"weave":  Weave(0.5, 3, DFS(NewStack(), 3)),
+		"levels": Layered(2, DFS(NewStack(), 4)),
+		"hex":    Hexagonal(DFS(NewStack(), 5)),
+	}
+	for name, gen := range generators {
+		maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, gen)
+		maze.ResetVisitedCells()
+		// braid maze a bit so there are several paths
+		for i := 2; i < w-2; i += 3 {
+			c := maze.cells[i][h/2]
+			for _, n := range maze.AdjacentCells(c, func(*cell) bool { return true }) {
+				if n.y == c.y && n.x == c.x+1 {
+					maze.RmWall(c, n)
+				}
+			}
+		}
+
+		bfsPath := make([]*cell, 0, w)
+		bfsVisited := make([]*cell, 0, w)
+		FindShortestPath(maze, maze.Begin(), maze.End(), &bfsPath, &bfsVisited)
+		maze.ResetVisitedCells()
+
+		path := make([]*cell, 0, w)
+		visited := make([]*cell, 0, w)
+		if !FindShortestPathAStar(maze, maze.Begin(), maze.End(), &path, &visited) {
+			t.Errorf("%s: path not found", name)
+		}
+		maze.ResetVisitedCells()
+		if len(path) != len(bfsPath) {
+			t.Errorf("%s: expected path of %d cells, got %d", name, len(bfsPath), len(path))
+		}
+		if path[0] != maze.End() || path[len(path)-1] != maze.Begin() {
+			t.Errorf("%s: path should go from end to start", name)
+		}
+		for i := 1; i < len(path); i++ {
+			if !isConnected(path[i], path[i-1]) {
+				t.Errorf("%s: path is broken at %v", name, path[i].point)
+			}
+		}
+	}
+}
+
+func TestBinaryTree(t *testing.T) {
+	w, h := 12, 8
+	maze, path := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, BinaryTree(1))
+	if len(path) != w*h {
+		t.Errorf("expected every cell in path, got %d", len(path))
+	}
+	doors := 0
+	for x := range maze.cells {
+		for y, c := range maze.cells[x] {
+			if c.right {
+				doors++
+			}
+			if c.down {
+				doors++
+			}
+			// top row and right column are corridors
+			if y == 0 && x < w-1 && !c.right || x == w-1 && y > 0 && !c.up {
+				t.Errorf("expected corridor at %v", c.point)
+			}
+		}
+	}
+	// perfect maze
+	if doors != w*h-1 {
+		t.Errorf("expected %d doors, got %d", w*h-1, doors)
+	}
+	again, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, BinaryTree(1))
+	if again.String() != maze.String() {
+		t.Error("expected same maze for same seed")
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"image"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// Tiled returns generator which splits maze into tile x tile squares,
+// carves each of them with DFS in own goroutine and joins tiles by
+// opening one wall per edge of random spanning tree of tiles, so maze
+// stays perfect. Every tile has own RNG seeded from seed, maze is the same
+// for any GOMAXPROCS. Tiles only pick walls concurrently, walls are
+// removed tile by tile afterwards so observers see ordinary carving
+func Tiled(tile int, seed int64) func(*Maze) (*Maze, []*cell) {
+	return func(m *Maze) (*Maze, []*cell) {
+		g, ok := m.topology.(squareGrid)
+		if h, v := g.wraps(m); !ok || h || v {
+			panic("tiled generation needs square grid without wrap")
+		}
+		for x := range m.cells {
+			for _, c := range m.cells[x] {
+				if c.masked {
+					panic("tiled generation doesn't support masked cells")
+				}
+			}
+		}
+		tiles, cols := tileGrid(m.w, m.h, tile)
+		paths := make([][]*cell, len(tiles))
+		walls := make([][][2]*cell, len(tiles))
+		parallel(len(tiles), func(i int) {
+			paths[i], walls[i] = m.carveTile(tiles[i], rand.New(rand.NewSource(tileSeed(seed, i))))
+		})
+		genPath := make([]*cell, 0, m.w*m.h)
+		for _, p := range paths {
+			genPath = append(genPath, p...)
+		}
+		// steps of walls were counted by tiles
+		ctl := m.ctl
+		m.ctl = nil
+		for _, tw := range walls {
+			for _, w := range tw {
+				m.RmWall(w[0], w[1])
+			}
+		}
+		m.ctl = ctl
+		if m.halted() {
+			return m, genPath
+		}
+		stitchTiles(rand.New(rand.NewSource(seed)), tiles, cols, func(p1, p2 point) {
+			m.RmWall(m.cells[p1.x][p1.y], m.cells[p2.x][p2.y])
+		})
+		return m, genPath
+	}
+}
+
+// carveTile picks walls of DFS maze inside tile and returns them in order
+// of carving, walls are left in place. Only visited of cells of tile is
+// touched so tiles can be carved concurrently
+func (m *Maze) carveTile(tile image.Rectangle, r *rand.Rand) ([]*cell, [][2]*cell) {
+	inside := func(c *cell) bool {
+		return image.Pt(c.x, c.y).In(tile) && !c.visited
+	}
+	current := m.cells[tile.Min.X][tile.Min.Y]
+	current.visited = true
+	carved := make([]*cell, 0, tile.Dx()*tile.Dy())
+	walls := make([][2]*cell, 0, tile.Dx()*tile.Dy())
+	stack := NewStack()
+	for !m.halted() {
+		unvisited := m.AdjacentCells(current, inside)
+		if len(unvisited) > 0 {
+			carved = append(carved, current)
+			next := unvisited[r.Intn(len(unvisited))]
+			stack.Push(current)
+			walls = append(walls, [2]*cell{current, next})
+			m.step()
+			current = next
+			current.visited = true
+		} else if stack.Len() > 0 {
+			current = stack.Pop()
+		} else {
+			break
+		}
+	}
+	return carved, walls
+}
+
+// PackedTiled returns generator of packed maze like Tiled, each tile is
+// carved to own packed maze and copied in place afterwards
+func PackedTiled(tile int, seed int64) func(*PackedMaze) *PackedMaze {
+	return func(m *PackedMaze) *PackedMaze {
+		tiles, cols := tileGrid(m.w, m.h, tile)
+		parts := make([]*PackedMaze, len(tiles))
+		parallel(len(tiles), func(i int) {
+			t := tiles[i]
+			parts[i] = NewPackedMaze(t.Dx(), t.Dy(), point{0, 0}, point{0, 0}, PackedDFS(tileSeed(seed, i)))
+		})
+		// tiles share words of packed doors, copy one by one
+		for i, part := range parts {
+			min := tiles[i].Min
+			for j := 0; j < part.Len(); j++ {
+				p := part.Point(j)
+				m.doors.set(m.Index(point{min.X + p.x, min.Y + p.y}), part.doors.get(j))
+			}
+		}
+		stitchTiles(rand.New(rand.NewSource(seed)), tiles, cols, func(p1, p2 point) {
+			m.RmWall(m.Index(p1), m.Index(p2))
+		})
+		return m
+	}
+}
+
+// tileGrid splits w x h grid to tiles row by row, tiles of last column
+// and row may be smaller. Returns tiles and number of columns
+func tileGrid(w, h, tile int) ([]image.Rectangle, int) {
+	if tile < 1 {
+		panic("tile should be > 0")
+	}
+	cols, rows := (w+tile-1)/tile, (h+tile-1)/tile
+	tiles := make([]image.Rectangle, 0, cols*rows)
+	for y := 0; y < h; y += tile {
+		for x := 0; x < w; x += tile {
+			tiles = append(tiles, image.Rect(x, y, x+tile, y+tile).Intersect(image.Rect(0, 0, w, h)))
+		}
+	}
+	return tiles, cols
+}
+
+// tileSeed returns seed of tile i, splitmix of maze seed and tile
+func tileSeed(seed int64, i int) int64 {
+	z := uint64(seed) + uint64(i+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
+
+// stitchTiles picks random spanning tree of tiles and calls open for
+// random pair of cells across shared edge of each tree edge
+func stitchTiles(r *rand.Rand, tiles []image.Rectangle, cols int, open func(p1, p2 point)) {
+	edges := [][2]int{}
+	for i := range tiles {
+		if i%cols < cols-1 {
+			edges = append(edges, [2]int{i, i + 1})
+		}
+		if i+cols < len(tiles) {
+			edges = append(edges, [2]int{i, i + cols})
+		}
+	}
+	r.Shuffle(len(edges), func(i, j int) { edges[i], edges[j] = edges[j], edges[i] })
+
+	parent := make([]int, len(tiles))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, e := range edges {
+		a, b := find(e[0]), find(e[1])
+		if a == b {
+			continue
+		}
+		parent[a] = b
+		t1, t2 := tiles[e[0]], tiles[e[1]]
+		if t1.Min.Y == t2.Min.Y {
+			y := t1.Min.Y + r.Intn(t1.Dy())
+			open(point{t1.Max.X - 1, y}, point{t2.Min.X, y})
+		} else {
+			x := t1.Min.X + r.Intn(t1.Dx())
+			open(point{x, t1.Max.Y - 1}, point{x, t2.Min.Y})
+		}
+	}
+}
+
+// parallel calls fn for 0..n-1 on GOMAXPROCS goroutines
+func parallel(n int, fn func(i int)) {
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestTiled(t *testing.T) {
+	w, h := 50, 37
+	// single column of tiles
+	narrow, _ := NewMaze(5, 20, point{0, 0}, point{4, 19}, Tiled(5, 1))
+	if reached, _ := narrow.reach([]*cell{narrow.Begin()}, 0); len(reached) != 5*20 {
+		t.Errorf("expected all cells reachable, got %d", len(reached))
+	}
+	maze, genPath := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, Tiled(8, 1))
+	maze.ResetVisitedCells()
+	if len(genPath) == 0 {
+		t.Error("expected generation path")
+	}
+
+	// perfect maze, one door less than cells and all cells reachable
+	doors := 0
+	for x := range maze.cells {
+		for _, c := range maze.cells[x] {
+			doors += len(maze.AdjacentCells(c, func(n *cell) bool { return isConnected(c, n) }))
+		}
+	}
+	reached, _ := maze.reach([]*cell{maze.Begin()}, 0)
+	if doors/2 != w*h-1 || len(reached) != w*h {
+		t.Errorf("expected perfect maze, got %d doors and %d reachable cells", doors/2, len(reached))
+	}
+
+	// same maze with one thread
+	procs := runtime.GOMAXPROCS(1)
+	again, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, Tiled(8, 1))
+	packed1 := NewPackedMaze(w, h, point{0, 0}, point{w - 1, h - 1}, PackedTiled(8, 1))
+	runtime.GOMAXPROCS(procs)
+	if again.String() != maze.String() {
+		t.Error("expected same maze for any GOMAXPROCS")
+	}
+
+	packed := NewPackedMaze(w, h, point{0, 0}, point{w - 1, h - 1}, PackedTiled(8, 1))
+	if packed.Unpack().String() != packed1.Unpack().String() {
+		t.Error("expected same packed maze for any GOMAXPROCS")
+	}
+	doors = 0
+	for i := 0; i < packed.Len(); i++ {
+		for _, n := range packed.Neighbors(i, nil) {
+			if n > i && packed.Connected(i, n) {
+				doors++
+			}
+		}
+	}
+	if _, found := FindPackedShortestPath(packed, packed.Begin(), packed.End(), nil); !found || doors != w*h-1 {
+		t.Errorf("expected perfect packed maze, got %d doors", doors)
+	}
+}
+
+func BenchmarkPackedTiled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewPackedMaze(benchSize, benchSize, point{0, 0}, point{benchSize - 1, benchSize - 1}, PackedTiled(64, 1))
+	}
+}
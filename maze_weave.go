@@ -0,0 +1,81 @@
+package main
+
+// Weave returns generator which lets passages tunnel under perpendicular
+// ones, generator may carve from a cell to the cell behind its neighbor
+// if neighbor has straight passage across. Each such move is offered with
+// probability p, decided by seed
+func Weave(p float64, seed int64, generator func(*Maze) (*Maze, []*cell)) func(*Maze) (*Maze, []*cell) {
+	return withTopology(weaveGrid{p, seed}, generator)
+}
+
+// weaveGrid is square grid where cells behind crossings are adjacent too
+type weaveGrid struct {
+	p    float64
+	seed int64
+}
+
+func (weaveGrid) planar() {}
+
+func (g weaveGrid) Neighbors(m *Maze, p point) []point {
+	points := squareGrid{}.Neighbors(m, p)
+	for i, d := range []point{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		mid := point{p.x + d.x, p.y + d.y}
+		far := point{p.x + 2*d.x, p.y + 2*d.y}
+		if !m.inside(far) || m.cells[far.x][far.y].masked || !g.chance(p, i) {
+			continue
+		}
+		if canCross(m.cells[mid.x][mid.y], d.y == 0) {
+			points = append(points, far)
+		}
+	}
+	return points
+}
+
+func (weaveGrid) Walls(m *Maze, p point, size float64) []wall {
+	return squareGrid{}.Walls(m, p, size)
+}
+
+func (weaveGrid) ImageSize(m *Maze, size float64) (float64, float64) {
+	return squareGrid{}.ImageSize(m, size)
+}
+
+// chance returns if crossing from p in direction dir is offered, it's
+// same for every call so maze stays stable for solvers
+func (g weaveGrid) chance(p point, dir int) bool {
+	// splitmix64 of cell, direction and seed
+	z := uint64(g.seed) + uint64(p.x)*0x9e3779b97f4a7c15 + uint64(p.y)*0xc2b2ae3d27d4eb4f + uint64(dir)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return float64(z>>11)/(1<<53) < g.p
+}
+
+// canCross returns if tunnel can go under c, horizontal tunnel needs
+// vertical passage through c and vice versa
+func canCross(c *cell, horizontal bool) bool {
+	if c.masked || c.tunnel {
+		return false
+	}
+	if horizontal {
+		return c.up && c.down && !c.left && !c.right
+	}
+	return c.left && c.right && !c.up && !c.down
+}
+
+// crossing returns cell between c1 and c2 which tunnel can go under, or
+// nil. Only weave mazes have tunnels, cells two apart on wrapped grid of
+// width or height 3 are neighbours across the edge
+func (m *Maze) crossing(c1, c2 *cell) *cell {
+	if _, ok := m.topology.(weaveGrid); !ok {
+		return nil
+	}
+	dx, dy := c2.x-c1.x, c2.y-c1.y
+	if !(dy == 0 && (dx == 2 || dx == -2) || dx == 0 && (dy == 2 || dy == -2)) {
+		return nil
+	}
+	mid := m.cells[c1.x+dx/2][c1.y+dy/2]
+	if !m.isAdjacent(c1, mid) || !m.isAdjacent(mid, c2) || !canCross(mid, dy == 0) {
+		return nil
+	}
+	return mid
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWeaveTunnel(t *testing.T) {
+	maze, _ := NewMaze(3, 3, point{0, 1}, point{2, 1}, Weave(0, 1, nil))
+	c := maze.cells
+	// vertical passage through centre
+	maze.RmWall(c[1][0], c[1][1])
+	maze.RmWall(c[1][1], c[1][2])
+	// tunnel under it
+	maze.RmWall(c[0][1], c[2][1])
+
+	if !c[1][1].tunnel {
+		t.Fatal("expected tunnel under centre")
+	}
+	if !isConnected(c[0][1], c[2][1]) || isConnected(c[0][1], c[1][1]) || isConnected(c[1][1], c[2][1]) {
+		t.Error("tunnel should connect cells under centre only")
+	}
+
+	path := make([]*cell, 0, 3)
+	visited := make([]*cell, 0, 3)
+	if !FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited) {
+		t.Fatal("path not found")
+	}
+	if len(path) != 2 {
+		t.Errorf("expected path through tunnel, got %d cells", len(path))
+	}
+	maze.ResetVisitedCells()
+
+	if s := maze.String(); !strings.Contains(s, "| S   |   E |") {
+		t.Errorf("unexpected maze\n%s", s)
+	}
+}
+
+func TestNoTunnelAcrossWrap(t *testing.T) {
+	maze, _ := NewMaze(3, 3, point{0, 1}, point{2, 1}, Wrapped(WrapTorus, nil))
+	c := maze.cells
+	maze.RmWall(c[1][0], c[1][1])
+	maze.RmWall(c[1][1], c[1][2])
+	if maze.crossing(c[0][1], c[2][1]) != nil || !maze.breakable(c[0][1], c[2][1]) {
+		t.Error("cells across wrapped edge should be neighbours")
+	}
+	// door across edge, not tunnel under c[1][1]
+	maze.RmWall(c[0][1], c[2][1])
+	if c[1][1].tunnel || !c[0][1].left || !c[2][1].right || c[0][1].right || c[2][1].left {
+		t.Error("expected door across wrapped edge")
+	}
+	if !isConnected(c[0][1], c[2][1]) || isConnected(c[0][1], c[1][1]) {
+		t.Error("expected cells connected across edge only")
+	}
+}
+
+func TestWeaveMaze(t *testing.T) {
+	w, h := 15, 15
+	maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, Weave(0.8, 1, DFS(NewStack(), 1)))
+
+	cells, doors, tunnels := 0, 0, 0
+	for x := range maze.cells {
+		for _, c := range maze.cells[x] {
+			cells++
+			if c.tunnel {
+				tunnels++
+			}
+			for _, n := range maze.AdjacentCells(c, func(*cell) bool { return true }) {
+				if isConnected(c, n) {
+					doors++
+				}
+			}
+		}
+	}
+	if tunnels == 0 {
+		t.Error("expected tunnels")
+	}
+	// tunnels are doors of spanning tree too
+	if doors/2 != cells-1 {
+		t.Errorf("expected %d doors for %d cells, got %d", cells-1, cells, doors/2)
+	}
+	maze.ResetVisitedCells()
+
+	path := make([]*cell, 0, w)
+	visited := make([]*cell, 0, w)
+	if !FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited) {
+		t.Fatal("path not found")
+	}
+	maze.ResetVisitedCells()
+
+	if f, err := os.Create(filepath.Join(t.TempDir(), "maze-weave.png")); err == nil {
+		if err = png.Encode(f, Draw(maze, white, black, 20, 20, 2)); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+)
+
+// Wrap is how edges of square maze connect to each other
+type Wrap int
+
+const (
+	WrapNone     Wrap = iota
+	WrapCylinder      // left and right edges are connected
+	WrapTorus         // left and right, top and bottom edges are connected
+)
+
+// Wrapped returns generator which connects edges of square maze according
+// to wrap, cells on opposite edges become adjacent
+func Wrapped(wrap Wrap, generator func(*Maze) (*Maze, []*cell)) func(*Maze) (*Maze, []*cell) {
+	return withTopology(squareGrid{wrap}, generator)
+}
+
+// DrawTiled returns maze image repeated 3 x 3 times to show how passages
+// continue across wrapped edges
+func DrawTiled(m *Maze, fill, border color.Color, cw, ch, ww int) *image.Paletted {
+	tile := Draw(m, fill, border, cw, ch, ww)
+	tw, th := tile.Bounds().Dx(), tile.Bounds().Dy()
+	img := image.NewPaletted(image.Rect(0, 0, 3*tw, 3*th), palette.WebSafe)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			at := image.Pt(x*tw, y*th)
+			draw.Draw(img, tile.Bounds().Add(at), tile, image.Point{}, draw.Src)
+		}
+	}
+	return img
+}
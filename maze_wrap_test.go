@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWrappedMaze(t *testing.T) {
+	w, h := 8, 6
+	for _, wrap := range []Wrap{WrapNone, WrapCylinder, WrapTorus} {
+		maze, _ := NewMaze(w, h, point{0, 0}, point{w - 1, h - 1}, Wrapped(wrap, DFS(NewStack(), 1)))
+
+		cells, doors, across := 0, 0, 0
+		for x := range maze.cells {
+			for _, c := range maze.cells[x] {
+				cells++
+				n := maze.AdjacentCells(c, func(*cell) bool { return true })
+				if wrap == WrapTorus && len(n) != 4 {
+					t.Errorf("torus cell %v has %d neighbours", c.point, len(n))
+				}
+				for _, next := range n {
+					if isConnected(c, next) {
+						doors++
+					}
+				}
+				if c.x == 0 && c.left || c.y == 0 && c.up {
+					across++
+				}
+			}
+		}
+		if doors/2 != cells-1 {
+			t.Errorf("wrap %d: expected %d doors for %d cells, got %d", wrap, cells-1, cells, doors/2)
+		}
+		if wrap == WrapNone && across > 0 || wrap != WrapNone && across == 0 {
+			t.Errorf("wrap %d: unexpected %d doors across edges", wrap, across)
+		}
+		maze.ResetVisitedCells()
+
+		path := make([]*cell, 0, w)
+		visited := make([]*cell, 0, w)
+		if !FindShortestPath(maze, maze.Begin(), maze.End(), &path, &visited) {
+			t.Errorf("wrap %d: path not found", wrap)
+		}
+		maze.ResetVisitedCells()
+
+		if wrap != WrapTorus {
+			continue
+		}
+		if f, err := os.Create(filepath.Join(t.TempDir(), "maze-torus-tiled.png")); err == nil {
+			if err = png.Encode(f, DrawTiled(maze, white, black, 20, 20, 2)); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+		}
+	}
+}
+
+func TestWrappedMazeDoorAcrossEdge(t *testing.T) {
+	maze, _ := NewMaze(3, 3, point{0, 1}, point{2, 1}, Wrapped(WrapTorus, nil))
+	c := maze.cells
+	maze.RmWall(c[0][1], c[2][1])
+	maze.RmWall(c[1][0], c[1][2])
+	if !c[0][1].left || !c[2][1].right || !c[1][0].up || !c[1][2].down {
+		t.Error("expected openings in border")
+	}
+	if !isConnected(c[0][1], c[2][1]) || !isConnected(c[1][2], c[1][0]) {
+		t.Error("expected cells across edges connected")
+	}
+	lines := strings.Split(maze.String(), "\n")
+	if lines[0] != "+---+   +---+" || lines[3] != "  S |   | E  " || lines[6] != "+---+   +---+" {
+		t.Errorf("unexpected maze\n%s", maze)
+	}
+
+	var svg bytes.Buffer
+	if err := DrawSVG(&svg, maze, white, black, 10, 10, 2); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(svg.String(), "M0 10v10") || strings.Contains(svg.String(), "M10 0h10") {
+		t.Errorf("expected openings in border, got %s", svg.String())
+	}
+
+	tiled := DrawTiled(maze, white, black, 10, 10, 2)
+	if b := tiled.Bounds(); b.Dx() != 90 || b.Dy() != 90 {
+		t.Errorf("unexpected tiled size %v", b)
+	}
+}
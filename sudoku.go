@@ -479,6 +479,16 @@ func printState(s [9][9]int) {
 
 // Draw SudokuBoard with cellSize in px
 func (s *Sudoku) Draw(cellSize int) (*image.RGBA, error) {
+	return drawBoard(s.result, cellSize, false)
+}
+
+// DrawPuzzle draws initial position with empty cells blank
+func (s *Sudoku) DrawPuzzle(cellSize int) (*image.RGBA, error) {
+	return drawBoard(s.initPos, cellSize, true)
+}
+
+// drawBoard draws board of state, zeros are left blank if blanks is set
+func drawBoard(state [9][9]int, cellSize int, blanks bool) (*image.RGBA, error) {
 	black := color.RGBA{0, 0, 0, 255}
 	cellPadding := cellSize / 20
 	box3x3Padding := cellSize / 20
@@ -515,8 +525,10 @@ func (s *Sudoku) Draw(cellSize int) (*image.RGBA, error) {
 					// draw cell
 					draw.Draw(cell, cell.Bounds(), &image.Uniform{white}, image.ZP, draw.Src)
 					// draw number
-					d.Dot = digitPos
-					d.DrawString(strconv.Itoa(s.result[i*3+x][j*3+y]))
+					if digit := state[i*3+x][j*3+y]; digit > 0 || !blanks {
+						d.Dot = digitPos
+						d.DrawString(strconv.Itoa(digit))
+					}
 
 					draw.Draw(
 						box3x3,